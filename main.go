@@ -1,42 +1,580 @@
 package main
 
 import (
+	"bufio"
 	"flag"
+	"fmt"
+	"log"
 	"math"
 	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/crp"
 	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/exporter"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/importer"
 	"github.com/lintang-b-s/navigatorx-partitioner/pkg/osmparser"
 	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/routing"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/server"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/snap"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+	"golang.org/x/exp/rand"
 )
 
-var (
-	mapFile = flag.String("f", "solo_jogja.osm.pbf", "openstreeetmap file buat road network graphnya")
-)
+// graphFlags are the flags every command uses to load the road network graph.
+type graphFlags struct {
+	mapFile   *string
+	graphFile *string
+	format    *string
+	coordFile *string
+	renumber  *string
+}
+
+func addGraphFlags(fs *flag.FlagSet) *graphFlags {
+	return &graphFlags{
+		mapFile:   fs.String("f", "solo_jogja.osm.pbf", "openstreeetmap file buat road network graphnya"),
+		graphFile: fs.String("g", "", "binary graph file. loaded instead of parsing the openstreetmap file if it exists, written after parsing otherwise"),
+		format:    fs.String("format", "osm", "input graph format: osm, dimacs, metis or csv"),
+		coordFile: fs.String("co", "", "coordinate file for the dimacs (.co) and metis formats"),
+		renumber:  fs.String("renumber", "", "renumber the nodes after import: hilbert, morton, bfs or dfs, ignored for a graph file that is already renumbered"),
+	}
+}
 
+// usage: navigatorx-partitioner [partition|export|serve|compare|customize|query|locate] [flags]
 func main() {
+	cmd := "partition"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "partition":
+		err = runPartition(args)
+	case "export":
+		err = runExport(args)
+	case "serve":
+		err = runServe(args)
+	case "compare":
+		err = runCompare(args)
+	case "customize":
+		err = runCustomize(args)
+	case "query":
+		err = runQuery(args)
+	case "locate":
+		err = runLocate(args)
+	default:
+		err = fmt.Errorf("unknown command %q, expected partition, export, serve, compare, customize, query or locate", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runPartition(args []string) error {
+	fs := flag.NewFlagSet("partition", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	seed := fs.Int64("seed", 0, "seed of the random number generators and kaffpa, runs with the same seed and input produce identical .mlp files")
+	cellPartitioner := fs.String("partitioner", partitioner.CELL_PARTITIONER_KAFFPA, "partitioner of every cell: kaffpa, flowcutter, or the geometric baselines rcb, hilbert and s2")
+	naturalCuts := fs.Bool("natural-cuts", false, "contract the fragments between natural cuts (PUNCH filter phase) before partitioning every cell")
+	edgeWeight := fs.String("edge-weight", string(partitioner.EDGE_WEIGHT_TIME), "weight of the cut edges minimized by the partitioner: uniform, time, distance or roadclass")
+	tinyCuts := fs.Bool("tiny-cuts", false, "contract dangling trees and degree 2 chains into weighted super-nodes before partitioning every cell")
+	driverMode := fs.String("mode", string(partitioner.DRIVER_MODE_TOP_DOWN), "order in which the levels are built: topdown, bottomup (smallest cells first, then adjacent cells merged) or hybrid (top level top down, the rest bottom up)")
+	runName := fs.String("name", "test_5_level_crp", "name of the run, every output file is prefixed with <partitioner>_<name>")
+	refineRounds := fs.Int("refine", 0, "rounds of size-constrained label propagation that refine every level after it was partitioned, 0 disables refinement")
+	fs.Parse(args)
+
+	factory, err := partitioner.NewCellPartitionerFactory(*cellPartitioner)
+	if err != nil {
+		return err
+	}
+	edgeWeightMode, err := partitioner.ParseEdgeWeightMode(*edgeWeight)
+	if err != nil {
+		return err
+	}
+	mode, err := partitioner.ParseDriverMode(*driverMode)
+	if err != nil {
+		return err
+	}
+	name := *cellPartitioner
+	if *naturalCuts {
+		factory = partitioner.WithNaturalCuts(factory)
+		name += "_nc"
+	}
+	if *tinyCuts {
+		factory = partitioner.WithTinyCuts(factory)
+		name += "_tc"
+	}
+	if mode != partitioner.DRIVER_MODE_TOP_DOWN {
+		name += "_" + string(mode)
+	}
+
+	util.SetSeed(uint64(*seed))
+
 	dir := "data"
 	if _, err := os.Stat("dir"); os.IsNotExist(err) {
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
-	flag.Parse()
-	osmParser := osmparser.NewOSMParserV2()
-	processedNodes, graphStorage, streetDirection := osmParser.Parse(*mapFile)
 
-	graph := datastructure.NewGraph()
-	graph.InitGraph(processedNodes, graphStorage, streetDirection, osmParser.GetTagStringIdMap())
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
 
 	mlp := partitioner.NewMultilevelPartitioner(
 		[]int{int(math.Pow(2, 8)), int(math.Pow(2, 11)), int(math.Pow(2, 14)), int(math.Pow(2, 17)), int(math.Pow(2, 20))},
 		5,
 		graph,
+		*seed,
+	)
+
+	mlp.SetCellPartitioner(name, factory)
+	mlp.SetEdgeWeightMode(edgeWeightMode)
+	mlp.SetDriverMode(mode)
+	mlp.SetRefinementRounds(*refineRounds)
+	if err := mlp.Run(*runName); err != nil {
+		return err
+	}
+	if *refineRounds == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "level\tmoves\tcut edges\tboundary vertices\tmax cell size\n")
+	for _, r := range mlp.GetRefinementReports() {
+		fmt.Fprintf(tw, "%d\t%d\t%d -> %d\t%d -> %d\t%d -> %d\n", r.Level, r.Moves, r.Before.CutEdges, r.After.CutEdges,
+			r.Before.BoundaryVertices, r.After.BoundaryVertices, r.Before.MaxCellSize, r.After.MaxCellSize)
+	}
+	return tw.Flush()
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	outFormat := fs.String("to", "geojson", "export format: geojson, graphml or csv")
+	out := fs.String("o", "graph", "output file name without extension")
+	fs.Parse(args)
+
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+
+	switch *outFormat {
+	case "geojson":
+		return exporter.WriteGeoJSON(graph, *out+".geojson")
+	case "graphml":
+		return exporter.WriteGraphML(graph, *out+".graphml")
+	case "csv":
+		return exporter.WriteCSV(graph, *out+"_nodes.csv", *out+"_edges.csv")
+	default:
+		return fmt.Errorf("unknown export format %q", *outFormat)
+	}
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	dataDir := fs.String("dir", ".", "directory containing the .mlp partition files of the graph")
+	addr := fs.String("addr", "localhost:8080", "http listen address")
+	fs.Parse(args)
+
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+
+	return server.NewServer(graph, *dataDir).ListenAndServe(*addr)
+}
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	mlpA := fs.String("a", "", "first .mlp partition file")
+	mlpB := fs.String("b", "", "second .mlp partition file")
+	topCells := fs.Int("top", 10, "number of cells with the largest disagreement reported per level")
+	diff := fs.String("diff", "", "if set, the geojson diff of each level is written to <diff>_level_<level>.geojson")
+	fs.Parse(args)
+
+	if *mlpA == "" || *mlpB == "" {
+		return fmt.Errorf("compare needs two partition files, -a and -b")
+	}
+
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+	a, err := partitioner.ReadMLPFile(*mlpA)
+	if err != nil {
+		return err
+	}
+	b, err := partitioner.ReadMLPFile(*mlpB)
+	if err != nil {
+		return err
+	}
+
+	comparisons, err := partitioner.ComparePartitions(graph, a, b, *topCells)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "a: %s\nb: %s\n\n", *mlpA, *mlpB)
+	for _, c := range comparisons {
+		fmt.Fprintf(tw, "level %d\tcells\tcut edges\tboundary vertices\tcell size (min/avg/max)\n", c.Level)
+		for _, q := range []struct {
+			name string
+			q    partitioner.LevelQuality
+		}{{"a", c.A}, {"b", c.B}} {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d/%.1f/%d\n", q.name, q.q.NumCells, q.q.CutEdges, q.q.BoundaryVertices,
+				q.q.MinCellSize, q.q.AvgCellSize, q.q.MaxCellSize)
+		}
+		fmt.Fprintf(tw, "adjusted rand index: %.4f, normalized mutual information: %.4f\n",
+			c.AdjustedRandIndex, c.NormalizedMutualInfo)
+		fmt.Fprintf(tw, "cell of a\tnodes\tbest match in b\toverlap\tdisagreement\n")
+		for _, d := range c.Disagreements {
+			fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\n", d.CellID, d.NodeCount, d.BestMatchCellID, d.Overlap, d.Disagreement)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if *diff == "" {
+		return nil
+	}
+	for _, c := range comparisons {
+		if err := writeComparisonGeoJSON(fmt.Sprintf("%s_level_%d.geojson", *diff, c.Level), graph, a, b, c.Level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeComparisonGeoJSON(filename string, graph *datastructure.Graph, a, b *partitioner.MultilevelPartition, level int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return partitioner.WriteComparisonGeoJSON(f, graph, a, b, level)
+}
+
+func runCustomize(args []string) error {
+	fs := flag.NewFlagSet("customize", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	mlpFile := fs.String("mlp", "", ".mlp partition file of the graph")
+	metricName := fs.String("metric", "time", "metric of the clique weights: time or distance")
+	workers := fs.Int("workers", 0, "number of cells customized in parallel, GOMAXPROCS if 0")
+	out := fs.String("o", "crp", "output file name without extension, writes <o>.overlay and <o>_<metric>.weights")
+	fs.Parse(args)
+
+	if *mlpFile == "" {
+		return fmt.Errorf("customize needs a partition file, -mlp")
+	}
+	metric, err := datastructure.ParseMetric(*metricName)
+	if err != nil {
+		return err
+	}
+
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+	partition, err := partitioner.ReadMLPFile(*mlpFile)
+	if err != nil {
+		return err
+	}
+
+	overlay, err := crp.NewOverlay(graph, partition)
+	if err != nil {
+		return err
+	}
+	for level := 0; level < overlay.GetLevelCount(); level++ {
+		log.Printf("level %d: %d cells, %d boundary vertices, %d clique arcs", level, overlay.GetCellCount(level),
+			overlay.GetBoundaryVertexCount(level), overlay.GetCliqueSize(level))
+	}
+	if err := overlay.WriteToFile(*out + ".overlay"); err != nil {
+		return err
+	}
+
+	weights := overlay.Customize(metric, *workers)
+	return weights.WriteToFile(fmt.Sprintf("%s_%s.weights", *out, metric))
+}
+
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	overlayFile := fs.String("overlay", "crp.overlay", "overlay file written by customize")
+	weightsFile := fs.String("weights", "crp_time.weights", "weights file written by customize")
+	source := fs.Int("s", -1, "source node id, a random query benchmark is run if source or target is not set")
+	target := fs.Int("t", -1, "target node id")
+	queries := fs.Int("n", 1000, "number of random queries of the benchmark")
+	seed := fs.Int64("seed", 0, "seed of the random queries")
+	check := fs.Bool("check", false, "check the benchmark queries against dijkstra, bidirectional dijkstra and a*")
+	fs.Parse(args)
+
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+	overlay, err := crp.ReadOverlayFile(*overlayFile, graph)
+	if err != nil {
+		return err
+	}
+	weights, err := crp.ReadWeightsFile(*weightsFile, overlay)
+	if err != nil {
+		return err
+	}
+	mld := crp.NewMultilevelDijkstra(weights)
+
+	if *source >= 0 && *target >= 0 {
+		if *source >= graph.GetNodeCount() || *target >= graph.GetNodeCount() {
+			return fmt.Errorf("source and target must be smaller than the node count %d", graph.GetNodeCount())
+		}
+		start := time.Now()
+		result := mld.ShortestPath(int32(*source), int32(*target))
+		if !result.Found {
+			fmt.Printf("no path from %d to %d\n", *source, *target)
+			return nil
+		}
+		fmt.Printf("travel time: %.2f min, distance: %.1f m, edges: %d, settled nodes: %d, query time: %v\n",
+			result.TravelTime, result.Distance, len(result.Path), result.SettledNodes, time.Since(start))
+		fmt.Println(result.Path)
+		return nil
+	}
+
+	oracles := []struct {
+		name   string
+		search interface {
+			ShortestPath(source, target int32) routing.Result
+		}
+		elapsed    time.Duration
+		settled    int
+		mismatches int
+	}{
+		{name: "dijkstra", search: routing.NewDijkstra(graph, weights.GetMetric())},
+		{name: "bidirectional dijkstra", search: routing.NewBidirectionalDijkstra(graph, weights.GetMetric())},
+		{name: "a*", search: routing.NewAStar(graph, weights.GetMetric())},
+	}
+
+	rng := rand.New(rand.NewSource(uint64(*seed)))
+	var (
+		elapsed  time.Duration
+		settled  int
+		found    int
+		pathSize int
+	)
+	for i := 0; i < *queries; i++ {
+		s, t := int32(rng.Intn(graph.GetNodeCount())), int32(rng.Intn(graph.GetNodeCount()))
+		start := time.Now()
+		result := mld.ShortestPath(s, t)
+		elapsed += time.Since(start)
+		settled += result.SettledNodes
+		pathSize += len(result.Path)
+		if result.Found {
+			found++
+		}
+
+		if !*check {
+			continue
+		}
+		for j := range oracles {
+			start := time.Now()
+			expected := oracles[j].search.ShortestPath(s, t)
+			oracles[j].elapsed += time.Since(start)
+			oracles[j].settled += expected.SettledNodes
+			if expected.Found != result.Found || (expected.Found && math.Abs(expected.Cost-result.Cost) > 1e-6*math.Max(1, expected.Cost)) {
+				oracles[j].mismatches++
+				log.Printf("query %d -> %d: %s cost %f, multilevel dijkstra cost %f", s, t, oracles[j].name, expected.Cost, result.Cost)
+			}
+		}
+	}
+	if *queries > 0 {
+		fmt.Printf("%d queries, %d found, avg query time: %v, avg settled nodes: %d, avg path edges: %d\n", *queries, found,
+			elapsed/time.Duration(*queries), settled / *queries, pathSize / *queries)
+		if *check {
+			for _, oracle := range oracles {
+				fmt.Printf("%s: %d mismatches, avg query time: %v, avg settled nodes: %d\n", oracle.name, oracle.mismatches,
+					oracle.elapsed/time.Duration(*queries), oracle.settled / *queries)
+			}
+		}
+	}
+	return nil
+}
+
+func runLocate(args []string) error {
+	fs := flag.NewFlagSet("locate", flag.ExitOnError)
+	gf := addGraphFlags(fs)
+	mlpFile := fs.String("mlp", "", ".mlp partition file")
+	radius := fs.Float64("radius", 100, "snapping radius in meters for coordinates outside the lookup table")
+	lat := fs.Float64("lat", math.NaN(), "latitude of a single coordinate")
+	lon := fs.Float64("lon", math.NaN(), "longitude of a single coordinate")
+	in := fs.String("in", "", "csv file with one lat,lon coordinate per line, located in batch")
+	out := fs.String("o", "", "output csv file of the batch, stdout if not set")
+	fs.Parse(args)
+
+	if *mlpFile == "" {
+		return fmt.Errorf("locate needs a partition file, -mlp")
+	}
+	graph, err := gf.loadGraph()
+	if err != nil {
+		return err
+	}
+	partition, err := partitioner.ReadMLPFile(*mlpFile)
+	if err != nil {
+		return err
+	}
+	locator, err := snap.NewCellLocator(graph, partition, *radius)
+	if err != nil {
+		return err
+	}
+	log.Printf("cell locator lookup table has %d s2 cells", locator.GetTableSize())
+
+	if *in == "" {
+		if math.IsNaN(*lat) || math.IsNaN(*lon) {
+			return fmt.Errorf("locate needs a coordinate, -lat and -lon, or a csv file, -in")
+		}
+		cells, err := locator.Locate(*lat, *lon)
+		if err != nil {
+			return err
+		}
+		for level, cellId := range cells {
+			fmt.Printf("level %d: cell %d\n", level, cellId)
+		}
+		return nil
+	}
+
+	coords, err := readCoordinates(*in)
+	if err != nil {
+		return err
+	}
+	cells := locator.LocateBatch(coords, 0)
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("lat,lon")
+	for level := 0; level < partition.GetLevelCount(); level++ {
+		fmt.Fprintf(bw, ",level_%d", level)
+	}
+	bw.WriteString("\n")
+	for i, c := range coords {
+		fmt.Fprintf(bw, "%f,%f", c.Lat, c.Lon)
+		for level := 0; level < partition.GetLevelCount(); level++ {
+			if cells[i] == nil {
+				bw.WriteString(",")
+			} else {
+				fmt.Fprintf(bw, ",%d", cells[i][level])
+			}
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// readCoordinates reads one lat,lon pair per line, lines that do not start with a number (e.g. a header) are skipped.
+func readCoordinates(filename string) ([]datastructure.Coordinate, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	coords := make([]datastructure.Coordinate, 0)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		if len(fields) < 2 {
+			continue
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if errLat != nil || errLon != nil {
+			if len(coords) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: invalid coordinate %q", filename, lineNo, scanner.Text())
+		}
+		coords = append(coords, datastructure.NewCoordinate(lat, lon))
+	}
+	return coords, scanner.Err()
+}
+
+func (gf *graphFlags) loadGraph() (*datastructure.Graph, error) {
+	if *gf.graphFile != "" {
+		if _, err := os.Stat(*gf.graphFile); err == nil {
+			log.Printf("loading graph from %s", *gf.graphFile)
+			graph, err := datastructure.ReadGraphFromFile(*gf.graphFile)
+			if err != nil {
+				return nil, err
+			}
+			if graph.NodePermutation != nil {
+				// the graph was renumbered before it was saved, the bfs and dfs orders are not idempotent
+				// and renumbering again would break every partition computed for the saved node ids
+				if *gf.renumber != "" {
+					log.Printf("%s is already renumbered, ignoring -renumber %s", *gf.graphFile, *gf.renumber)
+				}
+				return graph, nil
+			}
+			return graph, gf.renumberNodes(graph)
+		}
+	}
+
+	var (
+		graph *datastructure.Graph
+		err   error
 	)
+	switch *gf.format {
+	case "osm":
+		osmParser := osmparser.NewOSMParserV2()
+		processedNodes, graphStorage, streetDirection := osmParser.Parse(*gf.mapFile)
 
-	err := mlp.RunMLPKaffpa("kaffpa_test_5_level_crp")
+		graph = datastructure.NewGraph()
+		err = graph.InitGraph(processedNodes, graphStorage, streetDirection, osmParser.GetTagStringIdMap())
+	case "dimacs":
+		graph, err = importer.LoadDimacs(*gf.mapFile, *gf.coordFile)
+	case "metis":
+		graph, err = importer.LoadMetis(*gf.mapFile, *gf.coordFile)
+	case "csv":
+		graph, err = importer.LoadEdgeList(*gf.mapFile)
+	default:
+		err = fmt.Errorf("unknown graph format %q", *gf.format)
+	}
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if err := gf.renumberNodes(graph); err != nil {
+		return nil, err
+	}
+
+	if *gf.graphFile != "" {
+		log.Printf("saving graph to %s", *gf.graphFile)
+		if err := graph.WriteGraphToFile(*gf.graphFile); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+func (gf *graphFlags) renumberNodes(graph *datastructure.Graph) error {
+	if *gf.renumber == "" {
+		return nil
 	}
+	log.Printf("renumbering nodes in %s order", *gf.renumber)
+	return graph.RenumberNodes(*gf.renumber)
 }
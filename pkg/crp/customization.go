@@ -0,0 +1,145 @@
+package crp
+
+import (
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// Weights are the clique weights of an overlay for one metric.
+// the weight of the clique arc from entry i to exit j of cell c is cliques[level][cliqueOffset[c] + i*exits(c) + j],
+// math.Inf(1) if the exit cannot be reached from the entry inside the cell.
+type Weights struct {
+	overlay *Overlay
+	metric  datastructure.Metric
+	cliques [][]float64
+}
+
+func (w *Weights) GetMetric() datastructure.Metric {
+	return w.metric
+}
+
+func (w *Weights) GetOverlay() *Overlay {
+	return w.overlay
+}
+
+// GetCliqueWeight returns the shortest path cost inside the cell from its entryIdx-th entry vertex to its exitIdx-th exit vertex.
+func (w *Weights) GetCliqueWeight(level, cellId int, entryIdx, exitIdx int32) float64 {
+	ol := w.overlay.levels[level]
+	exits := int64(ol.exitOffset[cellId+1] - ol.exitOffset[cellId])
+	return w.cliques[level][ol.cliqueOffset[cellId]+int64(entryIdx)*exits+int64(exitIdx)]
+}
+
+// Customize computes the clique weights of every cell for the metric, bottom up:
+// level 0 cliques are computed with dijkstra searches on the graph restricted to the cell,
+// higher level cliques with searches on the cliques and cut edges of the subcells one level below.
+// the cells of a level are customized in parallel by workers goroutines, GOMAXPROCS if workers <= 0.
+func (o *Overlay) Customize(metric datastructure.Metric, workers int) *Weights {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	w := &Weights{
+		overlay: o,
+		metric:  metric,
+		cliques: make([][]float64, len(o.levels)),
+	}
+	for level, ol := range o.levels {
+		w.cliques[level] = make([]float64, ol.cliqueOffset[ol.cellCount()])
+
+		cells := make(chan int, workers)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				search := datastructure.NewDijkstraSearch(o.nodeCount)
+				for cellId := range cells {
+					w.customizeCell(search, level, cellId)
+				}
+			}()
+		}
+		for cellId := 0; cellId < ol.cellCount(); cellId++ {
+			cells <- cellId
+		}
+		close(cells)
+		wg.Wait()
+
+		log.Printf("customized level %d: %d cells, %d clique arcs", level, ol.cellCount(), len(w.cliques[level]))
+	}
+	return w
+}
+
+// customizeCell fills the clique of the cell with one search per entry vertex.
+func (w *Weights) customizeCell(search *datastructure.DijkstraSearch, level, cellId int) {
+	ol := w.overlay.levels[level]
+	exits := ol.exits(cellId)
+	clique := w.cliques[level][ol.cliqueOffset[cellId]:ol.cliqueOffset[cellId+1]]
+
+	for i, entry := range ol.entries(cellId) {
+		if level == 0 {
+			w.searchGraph(search, int32(cellId), entry)
+		} else {
+			w.searchOverlay(search, level, int32(cellId), entry)
+		}
+		for j, exit := range exits {
+			clique[i*len(exits)+j] = search.GetDist(exit)
+		}
+		search.Reset()
+	}
+}
+
+// searchGraph runs dijkstra from source on the graph edges inside the level 0 cell.
+func (w *Weights) searchGraph(search *datastructure.DijkstraSearch, cellId int32, source int32) {
+	graph := w.overlay.graph
+	cellOf := w.overlay.levels[0].cellOf
+
+	search.Add(source, 0, 0, -1, -1)
+	for !search.IsEmpty() {
+		u, ok := search.Next()
+		if !ok {
+			continue
+		}
+		graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			if cellOf[edge.ToNodeID] == cellId {
+				dist := search.GetDist(u) + w.metric.Cost(edge)
+				search.Add(edge.ToNodeID, dist, dist, -1, -1)
+			}
+		})
+	}
+}
+
+// searchOverlay runs dijkstra from source on the overlay of the subcells of the cell one level below:
+// the clique arcs of the subcells and the graph edges between subcells inside the cell.
+func (w *Weights) searchOverlay(search *datastructure.DijkstraSearch, level int, cellId int32, source int32) {
+	graph := w.overlay.graph
+	ol := w.overlay.levels[level]
+	sub := w.overlay.levels[level-1]
+
+	search.Add(source, 0, 0, -1, -1)
+	for !search.IsEmpty() {
+		u, ok := search.Next()
+		if !ok {
+			continue
+		}
+		subCell := sub.cellOf[u]
+
+		if entryIdx := sub.entryIndex[u]; entryIdx != -1 {
+			for j, exit := range sub.exits(int(subCell)) {
+				dist := search.GetDist(u) + w.GetCliqueWeight(level-1, int(subCell), entryIdx, int32(j))
+				search.Add(exit, dist, dist, -1, -1)
+			}
+		}
+		if sub.exitIndex[u] != -1 {
+			graph.ForOutEdges(u, func(edge datastructure.Edge) {
+				v := edge.ToNodeID
+				if ol.cellOf[v] == cellId && sub.cellOf[v] != subCell {
+					dist := search.GetDist(u) + w.metric.Cost(edge)
+					search.Add(v, dist, dist, -1, -1)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package crp
+
+import (
+	"errors"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+)
+
+var (
+	ErrGraphMismatch = errors.New("overlay and graph have a different number of nodes")
+)
+
+// Overlay is the metric independent part of customizable route planning (delling et al.) built from a multilevel partition.
+// for every level and cell it keeps the entry vertices (nodes of the cell with an edge from another cell of the level)
+// and the exit vertices (nodes of the cell with an edge to another cell of the level).
+// the cell clique connects every entry vertex of a cell to every exit vertex, its weights are computed by Customize.
+type Overlay struct {
+	graph     *datastructure.Graph
+	nodeCount int
+	levels    []*overlayLevel
+}
+
+type overlayLevel struct {
+	cellOf []int32 // cell id of every node
+
+	// entry and exit vertices of cell c are entryNodes[entryOffset[c]:entryOffset[c+1]]
+	// and exitNodes[exitOffset[c]:exitOffset[c+1]], sorted by node id.
+	entryOffset []int32
+	entryNodes  []int32
+	exitOffset  []int32
+	exitNodes   []int32
+
+	cliqueOffset []int64 // offset of the entries x exits clique matrix of every cell in the level weights, len = cells + 1
+
+	// position of every node in the entry / exit vertices of its cell, -1 if the node is not an entry / exit vertex.
+	// not serialized, rebuilt by index.
+	entryIndex []int32
+	exitIndex  []int32
+}
+
+// NewOverlay computes the boundary vertices and the clique layout of every level of the partition.
+func NewOverlay(graph *datastructure.Graph, partition *partitioner.MultilevelPartition) (*Overlay, error) {
+	if graph.GetNodeCount() != partition.GetNodeCount() {
+		return nil, ErrGraphMismatch
+	}
+
+	o := &Overlay{
+		graph:     graph,
+		nodeCount: graph.GetNodeCount(),
+		levels:    make([]*overlayLevel, partition.GetLevelCount()),
+	}
+	for level := range o.levels {
+		o.levels[level] = newOverlayLevel(graph, partition.CellAssignment(level), partition.GetCellCount(level))
+	}
+	return o, nil
+}
+
+func newOverlayLevel(graph *datastructure.Graph, cellOf []int32, cellCount int) *overlayLevel {
+	ol := &overlayLevel{
+		cellOf:      cellOf,
+		entryOffset: make([]int32, cellCount+1),
+		exitOffset:  make([]int32, cellCount+1),
+	}
+
+	isEntry := make([]bool, len(cellOf))
+	isExit := make([]bool, len(cellOf))
+	for nodeID := int32(0); nodeID < int32(len(cellOf)); nodeID++ {
+		graph.ForOutEdges(nodeID, func(edge datastructure.Edge) {
+			if cellOf[edge.FromNodeID] != cellOf[edge.ToNodeID] {
+				isExit[edge.FromNodeID] = true
+				isEntry[edge.ToNodeID] = true
+			}
+		})
+	}
+
+	// counting sort of the boundary vertices by cell, nodes of a cell stay sorted by id
+	for nodeID, cellId := range cellOf {
+		if isEntry[nodeID] {
+			ol.entryOffset[cellId+1]++
+		}
+		if isExit[nodeID] {
+			ol.exitOffset[cellId+1]++
+		}
+	}
+	for c := 0; c < cellCount; c++ {
+		ol.entryOffset[c+1] += ol.entryOffset[c]
+		ol.exitOffset[c+1] += ol.exitOffset[c]
+	}
+
+	ol.entryNodes = make([]int32, ol.entryOffset[cellCount])
+	ol.exitNodes = make([]int32, ol.exitOffset[cellCount])
+	entryPos := make([]int32, cellCount)
+	exitPos := make([]int32, cellCount)
+	copy(entryPos, ol.entryOffset[:cellCount])
+	copy(exitPos, ol.exitOffset[:cellCount])
+	for nodeID, cellId := range cellOf {
+		if isEntry[nodeID] {
+			ol.entryNodes[entryPos[cellId]] = int32(nodeID)
+			entryPos[cellId]++
+		}
+		if isExit[nodeID] {
+			ol.exitNodes[exitPos[cellId]] = int32(nodeID)
+			exitPos[cellId]++
+		}
+	}
+
+	ol.cliqueOffset = make([]int64, cellCount+1)
+	for c := 0; c < cellCount; c++ {
+		entries := int64(ol.entryOffset[c+1] - ol.entryOffset[c])
+		exits := int64(ol.exitOffset[c+1] - ol.exitOffset[c])
+		ol.cliqueOffset[c+1] = ol.cliqueOffset[c] + entries*exits
+	}
+
+	ol.index()
+	return ol
+}
+
+// index builds entryIndex and exitIndex from the entry and exit vertices.
+func (ol *overlayLevel) index() {
+	ol.entryIndex = make([]int32, len(ol.cellOf))
+	ol.exitIndex = make([]int32, len(ol.cellOf))
+	for i := range ol.entryIndex {
+		ol.entryIndex[i] = -1
+		ol.exitIndex[i] = -1
+	}
+	for c := 0; c < ol.cellCount(); c++ {
+		for i, nodeID := range ol.entries(c) {
+			ol.entryIndex[nodeID] = int32(i)
+		}
+		for i, nodeID := range ol.exits(c) {
+			ol.exitIndex[nodeID] = int32(i)
+		}
+	}
+}
+
+func (ol *overlayLevel) cellCount() int {
+	return len(ol.entryOffset) - 1
+}
+
+func (ol *overlayLevel) entries(cellId int) []int32 {
+	return ol.entryNodes[ol.entryOffset[cellId]:ol.entryOffset[cellId+1]]
+}
+
+func (ol *overlayLevel) exits(cellId int) []int32 {
+	return ol.exitNodes[ol.exitOffset[cellId]:ol.exitOffset[cellId+1]]
+}
+
+func (o *Overlay) GetLevelCount() int {
+	return len(o.levels)
+}
+
+func (o *Overlay) GetNodeCount() int {
+	return o.nodeCount
+}
+
+func (o *Overlay) GetGraph() *datastructure.Graph {
+	return o.graph
+}
+
+func (o *Overlay) GetCellCount(level int) int {
+	return o.levels[level].cellCount()
+}
+
+func (o *Overlay) GetCellID(level int, nodeID int32) int32 {
+	return o.levels[level].cellOf[nodeID]
+}
+
+// GetEntryVertices returns the nodes of the cell with an edge from another cell of the level, sorted by node id.
+func (o *Overlay) GetEntryVertices(level, cellId int) []int32 {
+	return o.levels[level].entries(cellId)
+}
+
+// GetExitVertices returns the nodes of the cell with an edge to another cell of the level, sorted by node id.
+func (o *Overlay) GetExitVertices(level, cellId int) []int32 {
+	return o.levels[level].exits(cellId)
+}
+
+// GetEntryIndex returns the position of nodeID in the entry vertices of its cell, -1 if it is not an entry vertex.
+func (o *Overlay) GetEntryIndex(level int, nodeID int32) int32 {
+	return o.levels[level].entryIndex[nodeID]
+}
+
+// GetExitIndex returns the position of nodeID in the exit vertices of its cell, -1 if it is not an exit vertex.
+func (o *Overlay) GetExitIndex(level int, nodeID int32) int32 {
+	return o.levels[level].exitIndex[nodeID]
+}
+
+// GetBoundaryVertexCount returns the number of nodes that are an entry or exit vertex of their cell in the level.
+func (o *Overlay) GetBoundaryVertexCount(level int) int {
+	ol := o.levels[level]
+	count := 0
+	for nodeID := range ol.cellOf {
+		if ol.entryIndex[nodeID] != -1 || ol.exitIndex[nodeID] != -1 {
+			count++
+		}
+	}
+	return count
+}
+
+// GetCliqueSize returns the total number of clique arcs, entries x exits summed over the cells of the level.
+func (o *Overlay) GetCliqueSize(level int) int64 {
+	ol := o.levels[level]
+	return ol.cliqueOffset[len(ol.cliqueOffset)-1]
+}
@@ -0,0 +1,222 @@
+package crp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+/*
+overlay file layout (little endian):
+
+	magic "NXCO" | version u32 | nodeCount u32 | levelCount u32
+	per level: cellOf, entryOffset, entryNodes, exitOffset, exitNodes, each as count u32, [i32]
+
+weights file layout (little endian):
+
+	magic "NXCW" | version u32 | metric (len u32, bytes) | nodeCount u32 | levelCount u32
+	per level: count u32, [f64] clique weights
+*/
+
+const (
+	overlayFileMagic   = "NXCO"
+	weightsFileMagic   = "NXCW"
+	overlayFileVersion = uint32(1)
+)
+
+var (
+	ErrInvalidOverlayFile = errors.New("invalid overlay file")
+)
+
+// WriteToFile writes the boundary vertices of every level, the overlay can be reloaded with ReadOverlayFile.
+func (o *Overlay) WriteToFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	bw := datastructure.NewBinaryWriter(bufio.NewWriterSize(f, 1<<20))
+	bw.WriteBytes([]byte(overlayFileMagic))
+	bw.WriteUint32(overlayFileVersion)
+	bw.WriteUint32(uint32(o.nodeCount))
+	bw.WriteUint32(uint32(len(o.levels)))
+	for _, ol := range o.levels {
+		for _, arr := range [][]int32{ol.cellOf, ol.entryOffset, ol.entryNodes, ol.exitOffset, ol.exitNodes} {
+			bw.WriteInt32Slice(arr)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadOverlayFile loads an overlay written by WriteToFile for graph.
+func ReadOverlayFile(filename string, graph *datastructure.Graph) (*Overlay, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := datastructure.NewBinaryReader(data)
+
+	if err := readHeader(br, overlayFileMagic); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+	}
+	nodeCount, levelCount := br.ReadUint32(), br.ReadUint32()
+	if err := br.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+	}
+	if int(nodeCount) != graph.GetNodeCount() {
+		return nil, ErrGraphMismatch
+	}
+
+	o := &Overlay{
+		graph:     graph,
+		nodeCount: int(nodeCount),
+		levels:    make([]*overlayLevel, 0, levelCount),
+	}
+	for level := 0; level < int(levelCount); level++ {
+		arrs := make([][]int32, 5)
+		for i := range arrs {
+			arrs[i] = br.ReadInt32Slice()
+		}
+		if err := br.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+		}
+		ol := &overlayLevel{
+			cellOf:      arrs[0],
+			entryOffset: arrs[1],
+			entryNodes:  arrs[2],
+			exitOffset:  arrs[3],
+			exitNodes:   arrs[4],
+		}
+		if err := ol.validate(o.nodeCount); err != nil {
+			return nil, fmt.Errorf("%w: %s: level %d: %v", ErrInvalidOverlayFile, filename, level, err)
+		}
+
+		cellCount := ol.cellCount()
+		ol.cliqueOffset = make([]int64, cellCount+1)
+		for c := 0; c < cellCount; c++ {
+			entries := int64(ol.entryOffset[c+1] - ol.entryOffset[c])
+			exits := int64(ol.exitOffset[c+1] - ol.exitOffset[c])
+			ol.cliqueOffset[c+1] = ol.cliqueOffset[c] + entries*exits
+		}
+		ol.index()
+		o.levels = append(o.levels, ol)
+	}
+	return o, nil
+}
+
+// validate checks that the offsets and node ids read from a file are in range.
+func (ol *overlayLevel) validate(nodeCount int) error {
+	if len(ol.cellOf) != nodeCount || len(ol.entryOffset) == 0 || len(ol.entryOffset) != len(ol.exitOffset) {
+		return errors.New("inconsistent section sizes")
+	}
+	cellCount := len(ol.entryOffset) - 1
+	for _, cellId := range ol.cellOf {
+		if cellId < 0 || int(cellId) >= cellCount {
+			return fmt.Errorf("cell id %d out of range", cellId)
+		}
+	}
+	for _, bounds := range []struct {
+		offsets []int32
+		nodes   []int32
+	}{{ol.entryOffset, ol.entryNodes}, {ol.exitOffset, ol.exitNodes}} {
+		if bounds.offsets[0] != 0 || int(bounds.offsets[cellCount]) != len(bounds.nodes) {
+			return errors.New("boundary vertex offsets do not match the boundary vertices")
+		}
+		for c := 0; c < cellCount; c++ {
+			if bounds.offsets[c] > bounds.offsets[c+1] {
+				return errors.New("boundary vertex offsets are not sorted")
+			}
+		}
+		for _, nodeID := range bounds.nodes {
+			if nodeID < 0 || int(nodeID) >= nodeCount {
+				return fmt.Errorf("node id %d out of range", nodeID)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteToFile writes the clique weights, they can be reloaded with ReadWeightsFile for the same overlay.
+func (w *Weights) WriteToFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	bw := datastructure.NewBinaryWriter(bufio.NewWriterSize(f, 1<<20))
+	bw.WriteBytes([]byte(weightsFileMagic))
+	bw.WriteUint32(overlayFileVersion)
+	bw.WriteString(string(w.metric))
+	bw.WriteUint32(uint32(w.overlay.nodeCount))
+	bw.WriteUint32(uint32(len(w.cliques)))
+	for _, clique := range w.cliques {
+		bw.WriteFloat64Slice(clique)
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadWeightsFile loads clique weights written by Weights.WriteToFile for the overlay.
+func ReadWeightsFile(filename string, overlay *Overlay) (*Weights, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := datastructure.NewBinaryReader(data)
+
+	if err := readHeader(br, weightsFileMagic); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+	}
+	metricName := br.ReadString()
+	nodeCount, levelCount := br.ReadUint32(), br.ReadUint32()
+	if err := br.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+	}
+	metric, err := datastructure.ParseMetric(metricName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+	}
+	if int(nodeCount) != overlay.nodeCount || int(levelCount) != len(overlay.levels) {
+		return nil, fmt.Errorf("%w: %s does not belong to the overlay", ErrInvalidOverlayFile, filename)
+	}
+
+	w := &Weights{
+		overlay: overlay,
+		metric:  metric,
+		cliques: make([][]float64, levelCount),
+	}
+	for level := range w.cliques {
+		w.cliques[level] = br.ReadFloat64Slice()
+		if err := br.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidOverlayFile, filename, err)
+		}
+		if int64(len(w.cliques[level])) != overlay.GetCliqueSize(level) {
+			return nil, fmt.Errorf("%w: %s: level %d clique size does not match the overlay", ErrInvalidOverlayFile, filename, level)
+		}
+	}
+	return w, nil
+}
+
+// readHeader checks the magic and version at the start of an overlay or weights file.
+func readHeader(br *datastructure.BinaryReader, magic string) error {
+	if string(br.ReadBytes(len(magic))) != magic {
+		return errors.New("bad magic")
+	}
+	if version := br.ReadUint32(); version != overlayFileVersion {
+		if err := br.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("unsupported version %d, expected %d", version, overlayFileVersion)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package crp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/internal/testgraph"
+)
+
+func TestOverlayFileRoundTrip(t *testing.T) {
+	graph := testgraph.Grid(t, gridSize, 3)
+	overlay, err := NewOverlay(graph, gridPartition(graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := overlay.Customize(datastructure.METRIC_TRAVEL_TIME, 1)
+
+	dir := t.TempDir()
+	overlayFile, weightsFile := filepath.Join(dir, "grid.overlay"), filepath.Join(dir, "grid_time.weights")
+	if err := overlay.WriteToFile(overlayFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := weights.WriteToFile(weightsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	readOverlay, err := ReadOverlayFile(overlayFile, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readWeights, err := ReadWeightsFile(weightsFile, readOverlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readWeights.GetMetric() != weights.GetMetric() {
+		t.Fatalf("metric %s, expected %s", readWeights.GetMetric(), weights.GetMetric())
+	}
+	for level := 0; level < overlay.GetLevelCount(); level++ {
+		if readOverlay.GetCellCount(level) != overlay.GetCellCount(level) ||
+			readOverlay.GetBoundaryVertexCount(level) != overlay.GetBoundaryVertexCount(level) {
+			t.Fatalf("level %d: cells or boundary vertices differ after reading the overlay", level)
+		}
+		for i, w := range weights.cliques[level] {
+			if readWeights.cliques[level][i] != w {
+				t.Fatalf("level %d: clique weight %d is %f, expected %f", level, i, readWeights.cliques[level][i], w)
+			}
+		}
+	}
+
+	// every truncated file must be rejected
+	for _, filename := range []string{overlayFile, weightsFile} {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []int{0, 3, 6, len(data) / 2, len(data) - 1} {
+			truncated := filepath.Join(dir, "truncated")
+			if err := os.WriteFile(truncated, data[:size], 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if filename == overlayFile {
+				_, err = ReadOverlayFile(truncated, graph)
+			} else {
+				_, err = ReadWeightsFile(truncated, overlay)
+			}
+			if !errors.Is(err, ErrInvalidOverlayFile) {
+				t.Errorf("%s truncated to %d bytes: error %v, expected %v", filepath.Base(filename), size, err, ErrInvalidOverlayFile)
+			}
+		}
+	}
+}
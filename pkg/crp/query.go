@@ -0,0 +1,268 @@
+package crp
+
+import (
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+// QueryResult is the shortest path found by MultilevelDijkstra.
+type QueryResult struct {
+	Found        bool
+	Cost         float64 // cost of the path in the metric of the weights
+	TravelTime   float64 // sum of Edge.Weight along the path, minutes
+	Distance     float64 // sum of Edge.Dist along the path, meters
+	Path         []int32 // ids of the edges of the path in GraphStorage.EdgeStorage, from source to target
+	SettledNodes int     // nodes settled by the forward and backward search, before unpacking
+}
+
+// MultilevelDijkstra answers point to point queries with a bidirectional dijkstra on the overlay graph of the source and target:
+// nodes in the level 0 cell of the source or target are searched on the graph edges,
+// every other node v is searched on the cliques and cut edges of the highest level whose cell of v contains neither
+// the source nor the target. clique arcs of the resulting path are unpacked with a dijkstra search restricted to their cell.
+// a MultilevelDijkstra reuses its search buffers and must not be used by multiple goroutines at the same time.
+type MultilevelDijkstra struct {
+	weights *Weights
+	overlay *Overlay
+	graph   *datastructure.Graph
+
+	forward  *querySearch
+	backward *querySearch
+	unpack   *querySearch
+
+	source int32
+	target int32
+}
+
+func NewMultilevelDijkstra(weights *Weights) *MultilevelDijkstra {
+	n := weights.overlay.nodeCount
+	return &MultilevelDijkstra{
+		weights:  weights,
+		overlay:  weights.overlay,
+		graph:    weights.overlay.graph,
+		forward:  newQuerySearch(n),
+		backward: newQuerySearch(n),
+		unpack:   newQuerySearch(n),
+	}
+}
+
+// queryLevel returns the highest level at which the cell of nodeID contains neither the source nor the target,
+// -1 if nodeID is in the level 0 cell of the source or the target.
+func (q *MultilevelDijkstra) queryLevel(nodeID int32) int {
+	level := -1
+	for l, ol := range q.overlay.levels {
+		cellId := ol.cellOf[nodeID]
+		if cellId == ol.cellOf[q.source] || cellId == ol.cellOf[q.target] {
+			break
+		}
+		level = l
+	}
+	return level
+}
+
+// ShortestPath returns the shortest path from source to target, Found is false if target cannot be reached.
+func (q *MultilevelDijkstra) ShortestPath(source, target int32) QueryResult {
+	q.source, q.target = source, target
+	defer func() {
+		q.forward.Reset()
+		q.backward.Reset()
+	}()
+
+	best := math.Inf(1)
+	meeting := int32(-1)
+	update := func(nodeID int32) {
+		if d := q.forward.GetDist(nodeID) + q.backward.GetDist(nodeID); d < best {
+			best = d
+			meeting = nodeID
+		}
+	}
+
+	q.forward.add(source, 0, -1, -1, -1)
+	q.backward.add(target, 0, -1, -1, -1)
+	update(source)
+
+	settled := 0
+	for !q.forward.IsEmpty() || !q.backward.IsEmpty() {
+		minForward, minBackward := math.Inf(1), math.Inf(1)
+		if !q.forward.IsEmpty() {
+			minForward = q.forward.GetMinRank()
+		}
+		if !q.backward.IsEmpty() {
+			minBackward = q.backward.GetMinRank()
+		}
+		if minForward+minBackward >= best {
+			break
+		}
+
+		if minForward <= minBackward {
+			u, ok := q.forward.Next()
+			if !ok {
+				continue
+			}
+			settled++
+			q.relaxForward(u, update)
+		} else {
+			u, ok := q.backward.Next()
+			if !ok {
+				continue
+			}
+			settled++
+			q.relaxBackward(u, update)
+		}
+	}
+
+	result := QueryResult{SettledNodes: settled, Cost: best}
+	if meeting == -1 {
+		return result
+	}
+	result.Found = true
+
+	// the forward arcs are collected from the meeting node back to the source, so they are appended in reverse
+	forwardArcs := make([][]int32, 0)
+	for v := meeting; q.forward.GetParent(v) != -1; v = q.forward.GetParent(v) {
+		forwardArcs = append(forwardArcs, q.unpackArc(q.forward, v, q.forward.GetParent(v), v))
+	}
+	result.Path = make([]int32, 0)
+	for i := len(forwardArcs) - 1; i >= 0; i-- {
+		result.Path = append(result.Path, forwardArcs[i]...)
+	}
+	for v := meeting; q.backward.GetParent(v) != -1; v = q.backward.GetParent(v) {
+		result.Path = append(result.Path, q.unpackArc(q.backward, v, v, q.backward.GetParent(v))...)
+	}
+
+	for _, edgeID := range result.Path {
+		edge := q.graph.GetOutEdge(edgeID)
+		result.TravelTime += edge.Weight
+		result.Distance += edge.Dist
+	}
+	return result
+}
+
+// relaxForward relaxes the arcs leaving u in the overlay graph of the query.
+func (q *MultilevelDijkstra) relaxForward(u int32, update func(nodeID int32)) {
+	level := q.queryLevel(u)
+	dist := q.forward.GetDist(u)
+
+	if level == -1 {
+		q.graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			if q.forward.add(edge.ToNodeID, dist+q.weights.metric.Cost(edge), u, edge.EdgeID, -1) {
+				update(edge.ToNodeID)
+			}
+		})
+		return
+	}
+
+	ol := q.overlay.levels[level]
+	cellId := ol.cellOf[u]
+	if entryIdx := ol.entryIndex[u]; entryIdx != -1 {
+		for j, exit := range ol.exits(int(cellId)) {
+			cost := q.weights.GetCliqueWeight(level, int(cellId), entryIdx, int32(j))
+			if q.forward.add(exit, dist+cost, u, -1, int8(level)) {
+				update(exit)
+			}
+		}
+	}
+	if ol.exitIndex[u] != -1 {
+		q.graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			if ol.cellOf[edge.ToNodeID] == cellId {
+				return
+			}
+			if q.forward.add(edge.ToNodeID, dist+q.weights.metric.Cost(edge), u, edge.EdgeID, -1) {
+				update(edge.ToNodeID)
+			}
+		})
+	}
+}
+
+// relaxBackward relaxes the arcs entering u in the overlay graph of the query.
+func (q *MultilevelDijkstra) relaxBackward(u int32, update func(nodeID int32)) {
+	level := q.queryLevel(u)
+	dist := q.backward.GetDist(u)
+
+	if level == -1 {
+		q.graph.ForInEdges(u, func(edge datastructure.Edge) {
+			if q.backward.add(edge.FromNodeID, dist+q.weights.metric.Cost(edge), u, edge.EdgeID, -1) {
+				update(edge.FromNodeID)
+			}
+		})
+		return
+	}
+
+	ol := q.overlay.levels[level]
+	cellId := ol.cellOf[u]
+	if exitIdx := ol.exitIndex[u]; exitIdx != -1 {
+		for i, entry := range ol.entries(int(cellId)) {
+			cost := q.weights.GetCliqueWeight(level, int(cellId), int32(i), exitIdx)
+			if q.backward.add(entry, dist+cost, u, -1, int8(level)) {
+				update(entry)
+			}
+		}
+	}
+	if ol.entryIndex[u] != -1 {
+		q.graph.ForInEdges(u, func(edge datastructure.Edge) {
+			if ol.cellOf[edge.FromNodeID] == cellId {
+				return
+			}
+			if q.backward.add(edge.FromNodeID, dist+q.weights.metric.Cost(edge), u, edge.EdgeID, -1) {
+				update(edge.FromNodeID)
+			}
+		})
+	}
+}
+
+// unpackArc returns the edges of the arc from -> to through which search reached nodeID.
+// graph edges are returned as is, clique arcs are unpacked with a dijkstra search restricted to the cell of the clique.
+func (q *MultilevelDijkstra) unpackArc(search *querySearch, nodeID, from, to int32) []int32 {
+	if edgeID := search.GetParentEdge(nodeID); edgeID != -1 {
+		return []int32{edgeID}
+	}
+
+	level := int(search.parentLevel[nodeID])
+	ol := q.overlay.levels[level]
+	cellId := ol.cellOf[from]
+	util.AssertPanic(ol.cellOf[to] == cellId, "clique arc endpoints must be in the same cell")
+
+	s := q.unpack
+	defer s.Reset()
+	s.add(from, 0, -1, -1, -1)
+	for !s.IsEmpty() {
+		u, ok := s.Next()
+		if !ok {
+			continue
+		}
+		if u == to {
+			break
+		}
+		q.graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			if ol.cellOf[edge.ToNodeID] == cellId {
+				s.add(edge.ToNodeID, s.GetDist(u)+q.weights.metric.Cost(edge), u, edge.EdgeID, -1)
+			}
+		})
+	}
+
+	return util.ReverseG(s.PathTo(to))
+}
+
+// querySearch is one direction of a query. the parent arc of a node is either the graph edge GetParentEdge,
+// or if that is -1, the clique arc of level parentLevel from (or, backward, to) the parent node.
+type querySearch struct {
+	*datastructure.DijkstraSearch
+	parentLevel []int8
+}
+
+func newQuerySearch(nodeCount int) *querySearch {
+	return &querySearch{
+		DijkstraSearch: datastructure.NewDijkstraSearch(nodeCount),
+		parentLevel:    make([]int8, nodeCount),
+	}
+}
+
+// add updates the distance of nodeID if dist improves it, returns true if it did.
+func (s *querySearch) add(nodeID int32, dist float64, parent, parentEdge int32, parentLevel int8) bool {
+	if !s.Add(nodeID, dist, dist, parent, parentEdge) {
+		return false
+	}
+	s.parentLevel[nodeID] = parentLevel
+	return true
+}
@@ -0,0 +1,66 @@
+package crp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/internal/testgraph"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/routing"
+	"golang.org/x/exp/rand"
+)
+
+const gridSize = 32
+
+// gridPartition splits the grid into square cells of 4x4 nodes on level 0, 8x8 nodes on level 1
+// and 16x16 nodes on level 2.
+func gridPartition(graph *datastructure.Graph) *partitioner.MultilevelPartition {
+	sides := []int{4, 8, 16}
+	numCells := make([]int, len(sides))
+	for level, side := range sides {
+		numCells[level] = (gridSize / side) * (gridSize / side)
+	}
+
+	cellNumbers := make([]uint64, graph.GetNodeCount())
+	for nodeID := range cellNumbers {
+		row, col := nodeID/gridSize, nodeID%gridSize
+		offset := 0
+		for level, side := range sides {
+			cellId := (row/side)*(gridSize/side) + col/side
+			cellNumbers[nodeID] |= uint64(cellId) << offset
+			offset += int(math.Ceil(math.Log2(float64(numCells[level]))))
+		}
+	}
+	return partitioner.NewMultilevelPartition(numCells, cellNumbers)
+}
+
+func TestMultilevelDijkstra(t *testing.T) {
+	graph := testgraph.Grid(t, gridSize, 1)
+	overlay, err := NewOverlay(graph, gridPartition(graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	for _, metric := range []datastructure.Metric{datastructure.METRIC_TRAVEL_TIME, datastructure.METRIC_DISTANCE} {
+		query := NewMultilevelDijkstra(overlay.Customize(metric, 2))
+		dijkstra := routing.NewDijkstra(graph, metric)
+		for i := 0; i < 500; i++ {
+			source, target := int32(rng.Intn(graph.GetNodeCount())), int32(rng.Intn(graph.GetNodeCount()))
+			want := dijkstra.ShortestPath(source, target)
+			got := query.ShortestPath(source, target)
+			if got.Found != want.Found {
+				t.Fatalf("%s %d -> %d: found %v, dijkstra found %v", metric, source, target, got.Found, want.Found)
+			}
+			if !got.Found {
+				continue
+			}
+			if math.Abs(got.Cost-want.Cost) > 1e-6 {
+				t.Fatalf("%s %d -> %d: cost %f, dijkstra cost %f", metric, source, target, got.Cost, want.Cost)
+			}
+
+			testgraph.CheckPath(t, graph, metric, string(metric), source, target, got.Path, got.Cost)
+		}
+	}
+}
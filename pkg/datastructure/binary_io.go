@@ -0,0 +1,187 @@
+package datastructure
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// BinaryWriter writes little endian values to a buffered writer. the first error is kept and every later write
+// is skipped, so callers check it once with Flush.
+type BinaryWriter struct {
+	w   *bufio.Writer
+	buf [8]byte
+	err error
+}
+
+func NewBinaryWriter(w *bufio.Writer) *BinaryWriter {
+	return &BinaryWriter{w: w}
+}
+
+func (bw *BinaryWriter) WriteBytes(b []byte) {
+	if bw.err != nil {
+		return
+	}
+	_, bw.err = bw.w.Write(b)
+}
+
+func (bw *BinaryWriter) WriteUint8(v uint8) {
+	bw.buf[0] = v
+	bw.WriteBytes(bw.buf[:1])
+}
+
+func (bw *BinaryWriter) WriteBool(v bool) {
+	if v {
+		bw.WriteUint8(1)
+	} else {
+		bw.WriteUint8(0)
+	}
+}
+
+func (bw *BinaryWriter) WriteUint32(v uint32) {
+	binary.LittleEndian.PutUint32(bw.buf[:4], v)
+	bw.WriteBytes(bw.buf[:4])
+}
+
+func (bw *BinaryWriter) WriteInt32(v int32) {
+	bw.WriteUint32(uint32(v))
+}
+
+func (bw *BinaryWriter) WriteInt64(v int64) {
+	binary.LittleEndian.PutUint64(bw.buf[:8], uint64(v))
+	bw.WriteBytes(bw.buf[:8])
+}
+
+func (bw *BinaryWriter) WriteFloat64(v float64) {
+	binary.LittleEndian.PutUint64(bw.buf[:8], math.Float64bits(v))
+	bw.WriteBytes(bw.buf[:8])
+}
+
+func (bw *BinaryWriter) WriteString(s string) {
+	bw.WriteUint32(uint32(len(s)))
+	bw.WriteBytes([]byte(s))
+}
+
+func (bw *BinaryWriter) WriteInt32Slice(arr []int32) {
+	bw.WriteUint32(uint32(len(arr)))
+	for _, v := range arr {
+		bw.WriteInt32(v)
+	}
+}
+
+func (bw *BinaryWriter) WriteFloat64Slice(arr []float64) {
+	bw.WriteUint32(uint32(len(arr)))
+	for _, v := range arr {
+		bw.WriteFloat64(v)
+	}
+}
+
+// Flush returns the first write error or flushes the buffered writer.
+func (bw *BinaryWriter) Flush() error {
+	if bw.err != nil {
+		return bw.err
+	}
+	return bw.w.Flush()
+}
+
+// BinaryReader reads little endian values written by BinaryWriter from a byte slice. the first error is kept and
+// every later read returns the zero value, so callers check it once with Err.
+type BinaryReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func NewBinaryReader(data []byte) *BinaryReader {
+	return &BinaryReader{data: data}
+}
+
+// Err returns the first read error.
+func (br *BinaryReader) Err() error {
+	return br.err
+}
+
+func (br *BinaryReader) ReadBytes(n int) []byte {
+	if br.err != nil {
+		return nil
+	}
+	if n < 0 || br.pos+n > len(br.data) {
+		br.err = fmt.Errorf("unexpected end of data at offset %d", br.pos)
+		return nil
+	}
+	b := br.data[br.pos : br.pos+n]
+	br.pos += n
+	return b
+}
+
+func (br *BinaryReader) ReadUint8() uint8 {
+	b := br.ReadBytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (br *BinaryReader) ReadBool() bool {
+	return br.ReadUint8() != 0
+}
+
+func (br *BinaryReader) ReadUint32() uint32 {
+	b := br.ReadBytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (br *BinaryReader) ReadInt32() int32 {
+	return int32(br.ReadUint32())
+}
+
+func (br *BinaryReader) ReadInt64() int64 {
+	b := br.ReadBytes(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+func (br *BinaryReader) ReadFloat64() float64 {
+	b := br.ReadBytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+// ReadCount reads a section length and checks that the remaining data can hold count entries of at least entrySize bytes.
+func (br *BinaryReader) ReadCount(entrySize int) int {
+	count := int(br.ReadUint32())
+	if br.err == nil && count*entrySize > len(br.data)-br.pos {
+		br.err = fmt.Errorf("section with %d entries exceeds file size at offset %d", count, br.pos)
+		return 0
+	}
+	return count
+}
+
+func (br *BinaryReader) ReadString() string {
+	n := int(br.ReadUint32())
+	return string(br.ReadBytes(n))
+}
+
+func (br *BinaryReader) ReadInt32Slice() []int32 {
+	arr := make([]int32, br.ReadCount(4))
+	for i := range arr {
+		arr[i] = br.ReadInt32()
+	}
+	return arr
+}
+
+func (br *BinaryReader) ReadFloat64Slice() []float64 {
+	arr := make([]float64, br.ReadCount(8))
+	for i := range arr {
+		arr[i] = br.ReadFloat64()
+	}
+	return arr
+}
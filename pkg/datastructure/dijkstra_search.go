@@ -0,0 +1,106 @@
+package datastructure
+
+import "math"
+
+// DijkstraSearch is the reusable state of one dijkstra search direction: tentative distances, the parent node and edge
+// each node was reached with and the nodes touched by the current query, so that Reset costs only as much as the query.
+// a DijkstraSearch must not be used by multiple goroutines at the same time.
+type DijkstraSearch struct {
+	dist       []float64
+	parent     []int32
+	parentEdge []int32
+	settled    []bool
+	touched    []int32
+	pq         *MinHeap[int32]
+}
+
+func NewDijkstraSearch(nodeCount int) *DijkstraSearch {
+	s := &DijkstraSearch{
+		dist:       make([]float64, nodeCount),
+		parent:     make([]int32, nodeCount),
+		parentEdge: make([]int32, nodeCount),
+		settled:    make([]bool, nodeCount),
+		touched:    make([]int32, 0),
+		pq:         NewMinHeap[int32](),
+	}
+	for i := range s.dist {
+		s.dist[i] = math.Inf(1)
+		s.parent[i] = -1
+		s.parentEdge[i] = -1
+	}
+	return s
+}
+
+// Add updates the distance of nodeID if dist improves it and queues it with rank (dist plus a potential for a*).
+// parent and parentEdge are the node and edge nodeID was reached from, -1 if unused. returns true if dist improved.
+func (s *DijkstraSearch) Add(nodeID int32, dist, rank float64, parent, parentEdge int32) bool {
+	if dist >= s.dist[nodeID] {
+		return false
+	}
+	if math.IsInf(s.dist[nodeID], 1) {
+		s.touched = append(s.touched, nodeID)
+	}
+	s.dist[nodeID] = dist
+	s.parent[nodeID] = parent
+	s.parentEdge[nodeID] = parentEdge
+	s.pq.Insert(nodeID, rank)
+	return true
+}
+
+// Next extracts the queued node with the smallest rank and settles it, false if it was already settled.
+func (s *DijkstraSearch) Next() (int32, bool) {
+	u := s.pq.ExtractMin().Item
+	if s.settled[u] {
+		return u, false
+	}
+	s.settled[u] = true
+	return u, true
+}
+
+func (s *DijkstraSearch) IsEmpty() bool {
+	return s.pq.IsEmpty()
+}
+
+// GetMinRank returns the smallest rank in the queue, the queue must not be empty.
+func (s *DijkstraSearch) GetMinRank() float64 {
+	return s.pq.GetMin().Rank
+}
+
+// GetDist returns the tentative distance of nodeID, +Inf if it was not reached.
+func (s *DijkstraSearch) GetDist(nodeID int32) float64 {
+	return s.dist[nodeID]
+}
+
+func (s *DijkstraSearch) GetParent(nodeID int32) int32 {
+	return s.parent[nodeID]
+}
+
+func (s *DijkstraSearch) GetParentEdge(nodeID int32) int32 {
+	return s.parentEdge[nodeID]
+}
+
+func (s *DijkstraSearch) IsSettled(nodeID int32) bool {
+	return s.settled[nodeID]
+}
+
+// PathTo returns the parent edges from nodeID back to the search source, in the order they are followed.
+// for a forward search that is the path reversed, for a backward search the path from nodeID to the target.
+func (s *DijkstraSearch) PathTo(nodeID int32) []int32 {
+	path := make([]int32, 0)
+	for v := nodeID; s.parent[v] != -1; v = s.parent[v] {
+		path = append(path, s.parentEdge[v])
+	}
+	return path
+}
+
+// Reset clears the state of the touched nodes and the queue for the next query.
+func (s *DijkstraSearch) Reset() {
+	for _, nodeID := range s.touched {
+		s.dist[nodeID] = math.Inf(1)
+		s.parent[nodeID] = -1
+		s.parentEdge[nodeID] = -1
+		s.settled[nodeID] = false
+	}
+	s.touched = s.touched[:0]
+	s.pq.Clear()
+}
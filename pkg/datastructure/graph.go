@@ -1,9 +1,16 @@
 package datastructure
 
 import (
+	"errors"
+	"sort"
+
 	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
 )
 
+var (
+	ErrEdgeInfoMismatch = errors.New("every edge must have its extra info")
+)
+
 type Metadata struct {
 	MeanDegree       float64
 	ShortcutsCount   int64
@@ -13,11 +20,19 @@ type Metadata struct {
 	NodeCount        int
 }
 type Graph struct {
-	GraphStorage           *GraphStorage
-	ContractedNodes        []CHNode
-	Metadata               Metadata
-	ContractedFirstOutEdge [][]int32
-	ContractedFirstInEdge  [][]int32
+	GraphStorage    *GraphStorage
+	ContractedNodes []CHNode
+	Metadata        Metadata
+
+	// compressed sparse row adjacency. edges in GraphStorage are sorted by FromNodeID,
+	// out edges of node u are ContractedOutEdges[ContractedFirstOutEdge[u]:ContractedFirstOutEdge[u+1]]
+	// and in edges of node u are ContractedInEdges[ContractedFirstInEdge[u]:ContractedFirstInEdge[u+1]].
+	ContractedFirstOutEdge []int32 // offsets, len = nodes + 1
+	ContractedOutEdges     []int32 // edge ids
+	ContractedOutHead      []int32 // target node of each out edge
+	ContractedFirstInEdge  []int32 // offsets, len = nodes + 1
+	ContractedInEdges      []int32 // edge ids
+	ContractedInTail       []int32 // source node of each in edge
 
 	SCC                []int32 // map for nodeID -> sccID
 	SCCNodesCount      []int32 // map for sccID -> nodes count in scc
@@ -25,6 +40,8 @@ type Graph struct {
 
 	nextEdgeID int32
 
+	NodePermutation []int32 // id of each node before RenumberNodes, nil if the nodes were never renumbered
+
 	StreetDirection map[int][2]bool // 0 = forward, 1 = backward
 	TagStringIDMap  util.IDMap
 }
@@ -42,14 +59,23 @@ func NewGraph() *Graph {
 
 func (ch *Graph) InitGraph(processedNodes []CHNode,
 	graphStorage *GraphStorage, streetDirections map[string][2]bool,
-	tagStringIdMap util.IDMap) {
+	tagStringIdMap util.IDMap) error {
 
 	ch.TagStringIDMap = tagStringIdMap
 
 	gLen := len(processedNodes)
 
-	for streetName, direction := range streetDirections {
-		ch.StreetDirection[ch.TagStringIDMap.GetID(streetName)] = direction
+	// street names are assigned ids in sorted order, so that the ids do not depend on the map iteration order
+	streetNames := make([]string, 0, len(streetDirections))
+	for streetName := range streetDirections {
+		streetNames = append(streetNames, streetName)
+	}
+	sort.Strings(streetNames)
+	for _, streetName := range streetNames {
+		ch.StreetDirection[ch.TagStringIDMap.GetID(streetName)] = streetDirections[streetName]
+	}
+	if err := graphStorage.checkEdgeInfos(); err != nil {
+		return err
 	}
 	ch.GraphStorage = graphStorage
 
@@ -57,26 +83,53 @@ func (ch *Graph) InitGraph(processedNodes []CHNode,
 
 	copy(ch.ContractedNodes, processedNodes)
 
-	ch.Metadata.degrees = make([]int, gLen)
-	ch.Metadata.OutEdgeOrigCount = make([]int, gLen)
-	ch.Metadata.ShortcutsCount = 0
+	ch.buildAdjacency()
+	return nil
+}
 
-	edgeID := int32(0)
-	ch.ContractedFirstOutEdge = make([][]int32, len(ch.ContractedNodes))
-	ch.ContractedFirstInEdge = make([][]int32, len(ch.ContractedNodes))
+// buildAdjacency sorts the edges by source node and builds the csr in/out adjacency and metadata
+// from ContractedNodes and GraphStorage.
+func (ch *Graph) buildAdjacency() {
+	gLen := len(ch.ContractedNodes)
 
-	for _, edge := range ch.GraphStorage.EdgeStorage {
+	ch.GraphStorage.SortEdgesBySource(gLen)
+	edges := ch.GraphStorage.EdgeStorage
 
-		ch.ContractedFirstOutEdge[edge.FromNodeID] = append(ch.ContractedFirstOutEdge[edge.FromNodeID], int32(edgeID))
+	ch.Metadata.degrees = make([]int, gLen)
+	ch.Metadata.OutEdgeOrigCount = make([]int, gLen)
+	ch.Metadata.ShortcutsCount = 0
 
+	ch.ContractedFirstOutEdge = make([]int32, gLen+1)
+	ch.ContractedFirstInEdge = make([]int32, gLen+1)
+	for _, edge := range edges {
+		ch.ContractedFirstOutEdge[edge.FromNodeID+1]++
+		ch.ContractedFirstInEdge[edge.ToNodeID+1]++
 		ch.Metadata.OutEdgeOrigCount[edge.FromNodeID]++
+	}
+	for u := 0; u < gLen; u++ {
+		ch.ContractedFirstOutEdge[u+1] += ch.ContractedFirstOutEdge[u]
+		ch.ContractedFirstInEdge[u+1] += ch.ContractedFirstInEdge[u]
+	}
 
-		ch.ContractedFirstInEdge[edge.ToNodeID] = append(ch.ContractedFirstInEdge[edge.ToNodeID], int32(edgeID))
-
-		edgeID++
+	// edges are already sorted by source, so the out edge at position i is edge i.
+	ch.ContractedOutEdges = make([]int32, len(edges))
+	ch.ContractedOutHead = make([]int32, len(edges))
+	ch.ContractedInEdges = make([]int32, len(edges))
+	ch.ContractedInTail = make([]int32, len(edges))
+
+	inPos := make([]int32, gLen)
+	copy(inPos, ch.ContractedFirstInEdge[:gLen])
+	for i, edge := range edges {
+		ch.ContractedOutEdges[i] = int32(i)
+		ch.ContractedOutHead[i] = edge.ToNodeID
+
+		pos := inPos[edge.ToNodeID]
+		ch.ContractedInEdges[pos] = int32(i)
+		ch.ContractedInTail[pos] = edge.FromNodeID
+		inPos[edge.ToNodeID]++
 	}
 
-	ch.Metadata.EdgeCount = len(ch.GraphStorage.EdgeStorage)
+	ch.Metadata.EdgeCount = len(edges)
 	ch.Metadata.NodeCount = gLen
 }
 
@@ -101,15 +154,55 @@ func (ch *Graph) GetNode(nodeID int32) CHNode {
 }
 
 func (ch *Graph) GetNodeFirstOutEdges(nodeID int32) []int32 {
-	return ch.ContractedFirstOutEdge[nodeID]
+	return ch.ContractedOutEdges[ch.ContractedFirstOutEdge[nodeID]:ch.ContractedFirstOutEdge[nodeID+1]]
+}
+
+// GetOutNeighbors returns the target nodes of the out edges of nodeID, in the same order as GetNodeFirstOutEdges.
+func (ch *Graph) GetOutNeighbors(nodeID int32) []int32 {
+	return ch.ContractedOutHead[ch.ContractedFirstOutEdge[nodeID]:ch.ContractedFirstOutEdge[nodeID+1]]
 }
 
 func (ch *Graph) GetOutDegree(nodeID int32) int {
-	return len(ch.ContractedFirstOutEdge[nodeID])
+	return int(ch.ContractedFirstOutEdge[nodeID+1] - ch.ContractedFirstOutEdge[nodeID])
 }
 
 func (ch *Graph) GetNodeFirstInEdges(nodeID int32) []int32 {
-	return ch.ContractedFirstInEdge[nodeID]
+	return ch.ContractedInEdges[ch.ContractedFirstInEdge[nodeID]:ch.ContractedFirstInEdge[nodeID+1]]
+}
+
+// GetInNeighbors returns the source nodes of the in edges of nodeID, in the same order as GetNodeFirstInEdges.
+func (ch *Graph) GetInNeighbors(nodeID int32) []int32 {
+	return ch.ContractedInTail[ch.ContractedFirstInEdge[nodeID]:ch.ContractedFirstInEdge[nodeID+1]]
+}
+
+func (ch *Graph) GetInDegree(nodeID int32) int {
+	return int(ch.ContractedFirstInEdge[nodeID+1] - ch.ContractedFirstInEdge[nodeID])
+}
+
+// ForOutEdges calls handle for every edge that can be traversed from nodeID: the out edges of nodeID
+// and the bidirectional edges stored as in edges of nodeID. the edges are oriented so that FromNodeID is nodeID.
+func (ch *Graph) ForOutEdges(nodeID int32, handle func(edge Edge)) {
+	for _, edgeID := range ch.GetNodeFirstOutEdges(nodeID) {
+		handle(ch.GraphStorage.GetOutEdge(edgeID))
+	}
+	for _, edgeID := range ch.GetNodeFirstInEdges(nodeID) {
+		if edge := ch.GraphStorage.GetInEdge(edgeID); !edge.Directed {
+			handle(edge)
+		}
+	}
+}
+
+// ForInEdges calls handle for every edge that can be traversed into nodeID: the in edges of nodeID
+// and the bidirectional edges stored as out edges of nodeID. the edges are oriented so that ToNodeID is nodeID.
+func (ch *Graph) ForInEdges(nodeID int32, handle func(edge Edge)) {
+	for _, edgeID := range ch.GetNodeFirstInEdges(nodeID) {
+		handle(ch.GraphStorage.GetOutEdge(edgeID))
+	}
+	for _, edgeID := range ch.GetNodeFirstOutEdges(nodeID) {
+		if edge := ch.GraphStorage.GetInEdge(edgeID); !edge.Directed {
+			handle(edge)
+		}
+	}
 }
 
 func (ch *Graph) GetOutEdge(edgeID int32) Edge {
@@ -1,6 +1,7 @@
 package datastructure
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -42,6 +43,9 @@ type GraphStorage struct {
 	RoundaboutFlag   []int32
 	NodeTrafficLight []int32
 
+	OsmNodeIDs    []int64 // osm id of each node, nil if the graph was not parsed from openstreetmap
+	SyntheticNode []int32 // bit flag per node, set for the copies of barrier nodes. their OsmNodeIDs is the id of the barrier node
+
 	StartShortcutID int32
 
 	MapEdgeInfo []EdgeExtraInfo
@@ -108,6 +112,115 @@ func (gs *GraphStorage) SetTrafficLight(nodeID int32) {
 	gs.NodeTrafficLight[index] |= 1 << (nodeID % 32)
 }
 
+func (gs *GraphStorage) IsRoundabout(edgeID int32) bool {
+	index := int(math.Floor(float64(edgeID) / 32))
+	if index >= len(gs.RoundaboutFlag) {
+		return false
+	}
+	return (gs.RoundaboutFlag[index] & (1 << (edgeID % 32))) != 0
+}
+
+// SortEdgesBySource stably reorders EdgeStorage, MapEdgeInfo and RoundaboutFlag so that edges are grouped by FromNodeID,
+// and reassigns the edge ids to the new positions. does nothing if the edges are already sorted.
+func (gs *GraphStorage) SortEdgesBySource(nodeCount int) {
+	sorted := true
+	for i := 1; i < len(gs.EdgeStorage); i++ {
+		if gs.EdgeStorage[i-1].FromNodeID > gs.EdgeStorage[i].FromNodeID {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		for i := range gs.EdgeStorage {
+			gs.EdgeStorage[i].EdgeID = int32(i)
+		}
+		return
+	}
+
+	// counting sort on the source node
+	offsets := make([]int32, nodeCount+1)
+	for _, edge := range gs.EdgeStorage {
+		offsets[edge.FromNodeID+1]++
+	}
+	for u := 0; u < nodeCount; u++ {
+		offsets[u+1] += offsets[u]
+	}
+
+	newEdgeIDs := make([]int32, len(gs.EdgeStorage))
+	for oldID, edge := range gs.EdgeStorage {
+		newEdgeIDs[oldID] = offsets[edge.FromNodeID]
+		offsets[edge.FromNodeID]++
+	}
+
+	gs.permuteEdges(newEdgeIDs)
+}
+
+// checkEdgeInfos checks that every edge has its extra info, InitGraph and ReadGraphFromFile reject graphs without.
+func (gs *GraphStorage) checkEdgeInfos() error {
+	if len(gs.MapEdgeInfo) != len(gs.EdgeStorage) {
+		return fmt.Errorf("%w: %d edges but %d edge infos", ErrEdgeInfoMismatch, len(gs.EdgeStorage), len(gs.MapEdgeInfo))
+	}
+	return nil
+}
+
+// permuteEdges moves edge oldID to position newEdgeIDs[oldID] together with its extra info and roundabout flag.
+func (gs *GraphStorage) permuteEdges(newEdgeIDs []int32) {
+	edges := make([]Edge, len(gs.EdgeStorage))
+	edgeInfos := make([]EdgeExtraInfo, len(gs.MapEdgeInfo))
+	roundaboutFlag := make([]int32, (len(gs.EdgeStorage)+31)/32)
+
+	for oldID, edge := range gs.EdgeStorage {
+		newID := newEdgeIDs[oldID]
+		edge.EdgeID = newID
+		edges[newID] = edge
+		edgeInfos[newID] = gs.MapEdgeInfo[oldID]
+		if gs.IsRoundabout(int32(oldID)) {
+			roundaboutFlag[newID/32] |= 1 << (newID % 32)
+		}
+	}
+
+	gs.EdgeStorage = edges
+	gs.MapEdgeInfo = edgeInfos
+	gs.RoundaboutFlag = roundaboutFlag
+}
+
+func (gs *GraphStorage) SetOsmNodeID(nodeID int32, osmID int64, synthetic bool) {
+	if len(gs.OsmNodeIDs) <= int(nodeID) {
+		gs.OsmNodeIDs = append(gs.OsmNodeIDs, make([]int64, int(nodeID)-len(gs.OsmNodeIDs)+1)...)
+	}
+	gs.OsmNodeIDs[nodeID] = osmID
+
+	index := int(nodeID / 32)
+	if len(gs.SyntheticNode) <= index {
+		gs.SyntheticNode = append(gs.SyntheticNode, make([]int32, index-len(gs.SyntheticNode)+1)...)
+	}
+	if synthetic {
+		gs.SyntheticNode[index] |= 1 << (nodeID % 32)
+	}
+}
+
+// GetOsmNodeID returns the osm id of the node, 0 if unknown. copies of barrier nodes return the id of the barrier node.
+func (gs *GraphStorage) GetOsmNodeID(nodeID int32) int64 {
+	if int(nodeID) >= len(gs.OsmNodeIDs) {
+		return 0
+	}
+	return gs.OsmNodeIDs[nodeID]
+}
+
+// IsSyntheticNode returns true if the node is a copy of a barrier node created by the osm parser.
+func (gs *GraphStorage) IsSyntheticNode(nodeID int32) bool {
+	index := int(nodeID / 32)
+	if index >= len(gs.SyntheticNode) {
+		return false
+	}
+	return (gs.SyntheticNode[index] & (1 << (nodeID % 32))) != 0
+}
+
+// GetOsmWayID returns the id of the osm way the edge was created from, 0 if unknown.
+func (gs *GraphStorage) GetOsmWayID(edgeID int32) int64 {
+	return gs.MapEdgeInfo[edgeID].OsmWayID
+}
+
 func (gs *GraphStorage) GetTrafficLight(nodeID int32) bool {
 	index := int(math.Floor(float64(nodeID) / 32))
 
@@ -147,9 +260,11 @@ type EdgeExtraInfo struct {
 	RoadClass        uint8
 	RoadClassLink    uint8
 	Lanes            uint8
+	OsmWayID         int64
 }
 
-func NewEdgeExtraInfo(streetName int, roadClass, lanes, roadClassLink uint8, StartPointsIdx, EndPointsIdx uint32) EdgeExtraInfo {
+func NewEdgeExtraInfo(streetName int, roadClass, lanes, roadClassLink uint8, StartPointsIdx, EndPointsIdx uint32,
+	osmWayID int64) EdgeExtraInfo {
 	return EdgeExtraInfo{
 		StreetName:       streetName,
 		RoadClass:        roadClass,
@@ -157,6 +272,7 @@ func NewEdgeExtraInfo(streetName int, roadClass, lanes, roadClassLink uint8, Sta
 		Lanes:            lanes,
 		StartPointsIndex: StartPointsIdx,
 		EndPointsIndex:   EndPointsIdx,
+		OsmWayID:         osmWayID,
 	}
 }
 
@@ -193,9 +309,7 @@ func (gs *GraphStorage) IsShortcut(edgeID int32) bool {
 func (gs *GraphStorage) GetEdgeExtraInfo(edgeID int32, reverse bool) (EdgeExtraInfo, bool) {
 
 	if edgeID < gs.StartShortcutID {
-		index := int(math.Floor(float64(edgeID) / 32))
-		roundabout := (gs.RoundaboutFlag[index] & (1 << (edgeID % 32))) != 0
-		return gs.MapEdgeInfo[edgeID], roundabout
+		return gs.MapEdgeInfo[edgeID], gs.IsRoundabout(edgeID)
 	}
 
 	return gs.MapEdgeInfo[edgeID], false
@@ -0,0 +1,232 @@
+package datastructure
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+/*
+graph file layout (little endian):
+
+	magic "NXPG" | version uint32
+	nodes            : count, [lat f64, lon f64, orderPos i32, id i32]
+	edges            : count, [weight f64, dist f64, edgeID i32, to i32, from i32, via i32, directed u8]
+	map edge info    : count, [startPointsIdx u32, endPointsIdx u32, streetName i64, roadClass u8, roadClassLink u8, lanes u8, osmWayID i64]
+	global points    : count, [lat f64, lon f64]
+	roundabout flag  : count, [i32]
+	traffic light    : count, [i32]
+	startShortcutID  : i32
+	tag string id map: count, [id i64, len u32, bytes] sorted by id
+	street direction : count, [streetID i64, forward u8, backward u8] sorted by streetID
+	node permutation : count, [i32], empty if the nodes were never renumbered
+	osm node ids     : count, [i64]
+	synthetic node   : count, [i32]
+*/
+
+const (
+	graphFileMagic   = "NXPG"
+	graphFileVersion = uint32(3)
+)
+
+var (
+	ErrInvalidGraphFile = errors.New("invalid graph file")
+)
+
+// WriteGraphToFile serializes the nodes and graph storage to a compact binary file,
+// so the graph can be reloaded with ReadGraphFromFile without parsing the osm file again.
+func (ch *Graph) WriteGraphToFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	bw := NewBinaryWriter(bufio.NewWriterSize(f, 1<<20))
+	gs := ch.GraphStorage
+
+	bw.WriteBytes([]byte(graphFileMagic))
+	bw.WriteUint32(graphFileVersion)
+
+	bw.WriteUint32(uint32(len(ch.ContractedNodes)))
+	for _, node := range ch.ContractedNodes {
+		bw.WriteFloat64(node.Lat)
+		bw.WriteFloat64(node.Lon)
+		bw.WriteInt32(node.OrderPos)
+		bw.WriteInt32(node.ID)
+	}
+
+	bw.WriteUint32(uint32(len(gs.EdgeStorage)))
+	for _, edge := range gs.EdgeStorage {
+		bw.WriteFloat64(edge.Weight)
+		bw.WriteFloat64(edge.Dist)
+		bw.WriteInt32(edge.EdgeID)
+		bw.WriteInt32(edge.ToNodeID)
+		bw.WriteInt32(edge.FromNodeID)
+		bw.WriteInt32(edge.ViaNodeID)
+		bw.WriteBool(edge.Directed)
+	}
+
+	bw.WriteUint32(uint32(len(gs.MapEdgeInfo)))
+	for _, info := range gs.MapEdgeInfo {
+		bw.WriteUint32(info.StartPointsIndex)
+		bw.WriteUint32(info.EndPointsIndex)
+		bw.WriteInt64(int64(info.StreetName))
+		bw.WriteUint8(info.RoadClass)
+		bw.WriteUint8(info.RoadClassLink)
+		bw.WriteUint8(info.Lanes)
+		bw.WriteInt64(info.OsmWayID)
+	}
+
+	bw.WriteUint32(uint32(len(gs.GlobalPoints)))
+	for _, point := range gs.GlobalPoints {
+		bw.WriteFloat64(point.Lat)
+		bw.WriteFloat64(point.Lon)
+	}
+
+	bw.WriteInt32Slice(gs.RoundaboutFlag)
+	bw.WriteInt32Slice(gs.NodeTrafficLight)
+	bw.WriteInt32(gs.StartShortcutID)
+
+	tagIDs := make([]int, 0, len(ch.TagStringIDMap.IDToStr))
+	for id := range ch.TagStringIDMap.IDToStr {
+		tagIDs = append(tagIDs, id)
+	}
+	sort.Ints(tagIDs)
+	bw.WriteUint32(uint32(len(tagIDs)))
+	for _, id := range tagIDs {
+		bw.WriteInt64(int64(id))
+		bw.WriteString(ch.TagStringIDMap.IDToStr[id])
+	}
+
+	streetIDs := make([]int, 0, len(ch.StreetDirection))
+	for id := range ch.StreetDirection {
+		streetIDs = append(streetIDs, id)
+	}
+	sort.Ints(streetIDs)
+	bw.WriteUint32(uint32(len(streetIDs)))
+	for _, id := range streetIDs {
+		bw.WriteInt64(int64(id))
+		bw.WriteBool(ch.StreetDirection[id][0])
+		bw.WriteBool(ch.StreetDirection[id][1])
+	}
+
+	bw.WriteInt32Slice(ch.NodePermutation)
+
+	bw.WriteUint32(uint32(len(gs.OsmNodeIDs)))
+	for _, id := range gs.OsmNodeIDs {
+		bw.WriteInt64(id)
+	}
+	bw.WriteInt32Slice(gs.SyntheticNode)
+
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadGraphFromFile loads a graph written by WriteGraphToFile. the file is memory-mapped where the platform supports it.
+func ReadGraphFromFile(filename string) (*Graph, error) {
+	data, release, err := mapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	br := NewBinaryReader(data)
+
+	if string(br.ReadBytes(len(graphFileMagic))) != graphFileMagic {
+		return nil, fmt.Errorf("%w: bad magic in %s", ErrInvalidGraphFile, filename)
+	}
+	if version := br.ReadUint32(); version != graphFileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d in %s, expected %d", ErrInvalidGraphFile, version, filename, graphFileVersion)
+	}
+
+	nodes := make([]CHNode, br.ReadCount(24))
+	for i := range nodes {
+		nodes[i].Lat = br.ReadFloat64()
+		nodes[i].Lon = br.ReadFloat64()
+		nodes[i].OrderPos = br.ReadInt32()
+		nodes[i].ID = br.ReadInt32()
+	}
+
+	gs := NewGraphStorage()
+
+	gs.EdgeStorage = make([]Edge, br.ReadCount(33))
+	for i := range gs.EdgeStorage {
+		edge := &gs.EdgeStorage[i]
+		edge.Weight = br.ReadFloat64()
+		edge.Dist = br.ReadFloat64()
+		edge.EdgeID = br.ReadInt32()
+		edge.ToNodeID = br.ReadInt32()
+		edge.FromNodeID = br.ReadInt32()
+		edge.ViaNodeID = br.ReadInt32()
+		edge.Directed = br.ReadBool()
+	}
+
+	gs.MapEdgeInfo = make([]EdgeExtraInfo, br.ReadCount(27))
+	for i := range gs.MapEdgeInfo {
+		info := &gs.MapEdgeInfo[i]
+		info.StartPointsIndex = br.ReadUint32()
+		info.EndPointsIndex = br.ReadUint32()
+		info.StreetName = int(br.ReadInt64())
+		info.RoadClass = br.ReadUint8()
+		info.RoadClassLink = br.ReadUint8()
+		info.Lanes = br.ReadUint8()
+		info.OsmWayID = br.ReadInt64()
+	}
+
+	gs.GlobalPoints = make([]Coordinate, br.ReadCount(16))
+	for i := range gs.GlobalPoints {
+		gs.GlobalPoints[i].Lat = br.ReadFloat64()
+		gs.GlobalPoints[i].Lon = br.ReadFloat64()
+	}
+
+	gs.RoundaboutFlag = br.ReadInt32Slice()
+	gs.NodeTrafficLight = br.ReadInt32Slice()
+	gs.StartShortcutID = br.ReadInt32()
+
+	ch := NewGraph()
+
+	tagCount := br.ReadCount(12)
+	for i := 0; i < tagCount; i++ {
+		id := int(br.ReadInt64())
+		str := br.ReadString()
+		ch.TagStringIDMap.StrToID[str] = id
+		ch.TagStringIDMap.IDToStr[id] = str
+	}
+
+	streetCount := br.ReadCount(10)
+	for i := 0; i < streetCount; i++ {
+		id := int(br.ReadInt64())
+		forward := br.ReadBool()
+		backward := br.ReadBool()
+		ch.StreetDirection[id] = [2]bool{forward, backward}
+	}
+
+	if permutation := br.ReadInt32Slice(); len(permutation) > 0 {
+		ch.NodePermutation = permutation
+	}
+
+	if osmNodeCount := br.ReadCount(8); osmNodeCount > 0 {
+		gs.OsmNodeIDs = make([]int64, osmNodeCount)
+		for i := range gs.OsmNodeIDs {
+			gs.OsmNodeIDs[i] = br.ReadInt64()
+		}
+	}
+	gs.SyntheticNode = br.ReadInt32Slice()
+
+	if err := br.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidGraphFile, filename, err)
+	}
+	if err := gs.checkEdgeInfos(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidGraphFile, filename, err)
+	}
+
+	ch.GraphStorage = gs
+	ch.ContractedNodes = nodes
+	ch.buildAdjacency()
+	return ch, nil
+}
@@ -0,0 +1,28 @@
+package datastructure
+
+import "fmt"
+
+// Metric is the edge cost used by shortest path searches and the crp customization.
+type Metric string
+
+const (
+	METRIC_TRAVEL_TIME Metric = "time"     // Edge.Weight, minutes
+	METRIC_DISTANCE    Metric = "distance" // Edge.Dist, meters
+)
+
+func ParseMetric(name string) (Metric, error) {
+	switch Metric(name) {
+	case METRIC_TRAVEL_TIME, METRIC_DISTANCE:
+		return Metric(name), nil
+	default:
+		return "", fmt.Errorf("unknown metric %q, expected time or distance", name)
+	}
+}
+
+// Cost returns the cost of traversing the edge.
+func (m Metric) Cost(edge Edge) float64 {
+	if m == METRIC_DISTANCE {
+		return edge.Dist
+	}
+	return edge.Weight
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package datastructure
+
+import "os"
+
+// mapFile reads the whole file into memory on platforms without mmap support.
+func mapFile(filename string) ([]byte, func(), error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
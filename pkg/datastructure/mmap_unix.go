@@ -0,0 +1,31 @@
+//go:build unix
+
+package datastructure
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile memory-maps filename read-only. release must be called once the data is no longer used.
+func mapFile(filename string) ([]byte, func(), error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return []byte{}, func() {}, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,83 @@
+package datastructure
+
+// PriorityQueueNode is an item of a MinHeap with its rank.
+type PriorityQueueNode[T any] struct {
+	Rank float64
+	Item T
+}
+
+// MinHeap is a binary min heap without decrease key. dijkstra style searches insert an item again when its rank improves
+// and skip the stale entries when they are extracted.
+type MinHeap[T any] struct {
+	heap []PriorityQueueNode[T]
+}
+
+func NewMinHeap[T any]() *MinHeap[T] {
+	return &MinHeap[T]{
+		heap: make([]PriorityQueueNode[T], 0),
+	}
+}
+
+func (h *MinHeap[T]) Size() int {
+	return len(h.heap)
+}
+
+func (h *MinHeap[T]) IsEmpty() bool {
+	return len(h.heap) == 0
+}
+
+func (h *MinHeap[T]) Clear() {
+	h.heap = h.heap[:0]
+}
+
+func (h *MinHeap[T]) Insert(item T, rank float64) {
+	h.heap = append(h.heap, PriorityQueueNode[T]{Rank: rank, Item: item})
+	h.siftUp(len(h.heap) - 1)
+}
+
+// GetMin returns the node with the smallest rank without removing it. the heap must not be empty.
+func (h *MinHeap[T]) GetMin() PriorityQueueNode[T] {
+	return h.heap[0]
+}
+
+// ExtractMin removes and returns the node with the smallest rank. the heap must not be empty.
+func (h *MinHeap[T]) ExtractMin() PriorityQueueNode[T] {
+	min := h.heap[0]
+	last := len(h.heap) - 1
+	h.heap[0] = h.heap[last]
+	h.heap = h.heap[:last]
+	if last > 0 {
+		h.siftDown(0)
+	}
+	return min
+}
+
+func (h *MinHeap[T]) siftUp(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h.heap[parent].Rank <= h.heap[i].Rank {
+			return
+		}
+		h.heap[parent], h.heap[i] = h.heap[i], h.heap[parent]
+		i = parent
+	}
+}
+
+func (h *MinHeap[T]) siftDown(i int) {
+	n := len(h.heap)
+	for {
+		smallest := i
+		left, right := 2*i+1, 2*i+2
+		if left < n && h.heap[left].Rank < h.heap[smallest].Rank {
+			smallest = left
+		}
+		if right < n && h.heap[right].Rank < h.heap[smallest].Rank {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		h.heap[smallest], h.heap[i] = h.heap[i], h.heap[smallest]
+		i = smallest
+	}
+}
@@ -0,0 +1,222 @@
+package datastructure
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+// node orders supported by RenumberNodes.
+const (
+	NODE_ORDER_HILBERT = "hilbert" // along a hilbert curve over the node coordinates
+	NODE_ORDER_MORTON  = "morton"  // along a z-order curve over the node coordinates
+	NODE_ORDER_BFS     = "bfs"     // breadth first search order, ignoring the edge directions
+	NODE_ORDER_DFS     = "dfs"     // depth first search preorder, ignoring the edge directions
+)
+
+const spaceFillingCurveOrder = 16 // the bounding box of the nodes is divided into a 2^16 x 2^16 grid
+
+// RenumberNodes renumbers the nodes so that nodes close to each other in the given order get close ids,
+// which improves the cache behavior of every graph traversal. edges, edge infos, traffic lights, osm ids and the adjacency
+// are remapped, NodePermutation keeps the id every node had before the first renumbering.
+func (ch *Graph) RenumberNodes(order string) error {
+	var newIDs []int32
+	switch order {
+	case NODE_ORDER_HILBERT, NODE_ORDER_MORTON:
+		newIDs = ch.spaceFillingCurveOrder(order)
+	case NODE_ORDER_BFS, NODE_ORDER_DFS:
+		newIDs = ch.traversalOrder(order)
+	default:
+		return fmt.Errorf("unknown node order %q, expected hilbert, morton, bfs or dfs", order)
+	}
+
+	ch.permuteNodes(newIDs)
+	return nil
+}
+
+// GetOriginalNodeID returns the id nodeID had before the nodes were renumbered.
+func (ch *Graph) GetOriginalNodeID(nodeID int32) int32 {
+	if ch.NodePermutation == nil {
+		return nodeID
+	}
+	return ch.NodePermutation[nodeID]
+}
+
+// spaceFillingCurveOrder returns the new id of every node when sorting them by their position on the curve.
+func (ch *Graph) spaceFillingCurveOrder(order string) []int32 {
+	n := len(ch.ContractedNodes)
+	if n == 0 {
+		return nil
+	}
+
+	minLat, minLon := math.Inf(1), math.Inf(1)
+	maxLat, maxLon := math.Inf(-1), math.Inf(-1)
+	for _, node := range ch.ContractedNodes {
+		minLat, maxLat = math.Min(minLat, node.Lat), math.Max(maxLat, node.Lat)
+		minLon, maxLon = math.Min(minLon, node.Lon), math.Max(maxLon, node.Lon)
+	}
+
+	gridSize := float64(uint32(1)<<spaceFillingCurveOrder - 1)
+	toGrid := func(v, lo, hi float64) uint32 {
+		if hi <= lo {
+			return 0
+		}
+		return uint32((v - lo) / (hi - lo) * gridSize)
+	}
+
+	keys := make([]uint64, n)
+	for i, node := range ch.ContractedNodes {
+		x := toGrid(node.Lon, minLon, maxLon)
+		y := toGrid(node.Lat, minLat, maxLat)
+		if order == NODE_ORDER_HILBERT {
+			keys[i] = util.HilbertIndex(x, y, spaceFillingCurveOrder)
+		} else {
+			keys[i] = util.MortonIndex(x, y)
+		}
+	}
+
+	oldIDs := make([]int32, n)
+	for i := range oldIDs {
+		oldIDs[i] = int32(i)
+	}
+	sort.SliceStable(oldIDs, func(i, j int) bool {
+		return keys[oldIDs[i]] < keys[oldIDs[j]]
+	})
+
+	newIDs := make([]int32, n)
+	for newID, oldID := range oldIDs {
+		newIDs[oldID] = int32(newID)
+	}
+	return newIDs
+}
+
+// traversalOrder returns the new id of every node when numbering them in bfs or dfs order.
+// the traversal ignores edge directions and restarts from the smallest unvisited node for every connected component.
+func (ch *Graph) traversalOrder(order string) []int32 {
+	n := len(ch.ContractedNodes)
+	newIDs := make([]int32, n)
+	for i := range newIDs {
+		newIDs[i] = -1
+	}
+
+	nextID := int32(0)
+	visit := func(u int32) {
+		newIDs[u] = nextID
+		nextID++
+	}
+	neighbors := func(u int32, f func(v int32)) {
+		for _, v := range ch.GetOutNeighbors(u) {
+			f(v)
+		}
+		for _, v := range ch.GetInNeighbors(u) {
+			f(v)
+		}
+	}
+
+	queue := make([]int32, 0)
+	for s := int32(0); s < int32(n); s++ {
+		if newIDs[s] != -1 {
+			continue
+		}
+
+		if order == NODE_ORDER_BFS {
+			visit(s)
+			queue = append(queue[:0], s)
+			for head := 0; head < len(queue); head++ {
+				neighbors(queue[head], func(v int32) {
+					if newIDs[v] == -1 {
+						visit(v)
+						queue = append(queue, v)
+					}
+				})
+			}
+			continue
+		}
+
+		// iterative dfs, a node is numbered when it is popped for the first time
+		stack := append(queue[:0], s)
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if newIDs[u] != -1 {
+				continue
+			}
+			visit(u)
+
+			// push in reverse so that the neighbors are visited in adjacency order
+			start := len(stack)
+			neighbors(u, func(v int32) {
+				if newIDs[v] == -1 {
+					stack = append(stack, v)
+				}
+			})
+			for i, j := start, len(stack)-1; i < j; i, j = i+1, j-1 {
+				stack[i], stack[j] = stack[j], stack[i]
+			}
+		}
+		queue = stack
+	}
+	return newIDs
+}
+
+// permuteNodes moves node oldID to newIDs[oldID], remaps the edge endpoints and traffic lights and rebuilds the adjacency.
+func (ch *Graph) permuteNodes(newIDs []int32) {
+	n := len(ch.ContractedNodes)
+	gs := ch.GraphStorage
+
+	nodes := make([]CHNode, n)
+	permutation := make([]int32, n)
+	trafficLight := make([]int32, (n+31)/32)
+	var osmNodeIDs []int64
+	var syntheticNode []int32
+	if len(gs.OsmNodeIDs) > 0 {
+		osmNodeIDs = make([]int64, n)
+		syntheticNode = make([]int32, (n+31)/32)
+	}
+	for oldID, node := range ch.ContractedNodes {
+		newID := newIDs[oldID]
+		node.ID = newID
+		node.OrderPos = newID
+		nodes[newID] = node
+		permutation[newID] = ch.GetOriginalNodeID(int32(oldID))
+
+		if int(oldID/32) < len(gs.NodeTrafficLight) && gs.GetTrafficLight(int32(oldID)) {
+			trafficLight[newID/32] |= 1 << (newID % 32)
+		}
+		if osmNodeIDs != nil {
+			osmNodeIDs[newID] = gs.GetOsmNodeID(int32(oldID))
+			if gs.IsSyntheticNode(int32(oldID)) {
+				syntheticNode[newID/32] |= 1 << (newID % 32)
+			}
+		}
+	}
+
+	if len(ch.SCC) == n {
+		scc := make([]int32, n)
+		for oldID, sccID := range ch.SCC {
+			scc[newIDs[oldID]] = sccID
+		}
+		ch.SCC = scc
+	}
+
+	for i := range gs.EdgeStorage {
+		edge := &gs.EdgeStorage[i]
+		edge.FromNodeID = newIDs[edge.FromNodeID]
+		edge.ToNodeID = newIDs[edge.ToNodeID]
+		if edge.ViaNodeID >= 0 && int(edge.ViaNodeID) < n {
+			edge.ViaNodeID = newIDs[edge.ViaNodeID]
+		}
+	}
+
+	ch.ContractedNodes = nodes
+	ch.NodePermutation = permutation
+	gs.NodeTrafficLight = trafficLight
+	if osmNodeIDs != nil {
+		gs.OsmNodeIDs = osmNodeIDs
+		gs.SyntheticNode = syntheticNode
+	}
+
+	ch.buildAdjacency()
+}
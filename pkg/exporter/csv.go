@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// WriteCSV writes a node table (id,lat,lon,traffic_light,osm_id,synthetic) and an edge table with one row per edge.
+func WriteCSV(graph *datastructure.Graph, nodesFile, edgesFile string) error {
+	if err := writeNodesCSV(graph, nodesFile); err != nil {
+		return err
+	}
+	return writeEdgesCSV(graph, edgesFile)
+}
+
+func writeNodesCSV(graph *datastructure.Graph, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"id", "lat", "lon", "traffic_light", "osm_id", "synthetic"}); err != nil {
+		return err
+	}
+	for _, node := range graph.GetNodes() {
+		err := w.Write([]string{
+			strconv.Itoa(int(node.ID)),
+			strconv.FormatFloat(node.Lat, 'f', -1, 64),
+			strconv.FormatFloat(node.Lon, 'f', -1, 64),
+			strconv.FormatBool(hasTrafficLight(graph, node.ID)),
+			strconv.FormatInt(graph.GraphStorage.GetOsmNodeID(node.ID), 10),
+			strconv.FormatBool(graph.GraphStorage.IsSyntheticNode(node.ID)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeEdgesCSV(graph *datastructure.Graph, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	err = w.Write([]string{"id", "source", "target", "weight", "distance", "road_class", "street_name", "lanes", "roundabout", "direction", "osm_way_id"})
+	if err != nil {
+		return err
+	}
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		attr := getEdgeAttributes(graph, edgeID)
+		err := w.Write([]string{
+			strconv.Itoa(int(attr.edgeID)),
+			strconv.Itoa(int(attr.from)),
+			strconv.Itoa(int(attr.to)),
+			strconv.FormatFloat(attr.weight, 'f', -1, 64),
+			strconv.FormatFloat(attr.dist, 'f', -1, 64),
+			attr.roadClass,
+			attr.streetName,
+			strconv.Itoa(int(attr.lanes)),
+			strconv.FormatBool(attr.roundabout),
+			attr.direction,
+			strconv.FormatInt(attr.osmWayID, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/paulmach/orb"
+)
+
+const (
+	DIRECTION_ONEWAY        = "oneway"
+	DIRECTION_BIDIRECTIONAL = "bidirectional"
+)
+
+// edgeAttributes are the edge properties shared by all export formats.
+type edgeAttributes struct {
+	edgeID     int32
+	from       int32
+	to         int32
+	weight     float64
+	dist       float64
+	roadClass  string
+	streetName string
+	lanes      uint8
+	roundabout bool
+	direction  string
+	osmWayID   int64
+}
+
+func getEdgeAttributes(graph *datastructure.Graph, edgeID int32) edgeAttributes {
+	edge := graph.GetOutEdge(edgeID)
+	attr := edgeAttributes{
+		edgeID:    edge.EdgeID,
+		from:      edge.FromNodeID,
+		to:        edge.ToNodeID,
+		weight:    edge.Weight,
+		dist:      edge.Dist,
+		direction: DIRECTION_BIDIRECTIONAL,
+	}
+	if edge.Directed {
+		attr.direction = DIRECTION_ONEWAY
+	}
+
+	info, roundabout := graph.GraphStorage.GetEdgeExtraInfo(edgeID, false)
+	attr.roundabout = roundabout
+	attr.lanes = info.Lanes
+	attr.osmWayID = info.OsmWayID
+	attr.streetName = graph.TagStringIDMap.GetStr(info.StreetName)
+	attr.roadClass = graph.TagStringIDMap.GetStr(int(info.RoadClass))
+	if attr.roadClass == "" {
+		attr.roadClass = graph.TagStringIDMap.GetStr(int(info.RoadClassLink))
+	}
+	return attr
+}
+
+// EdgeGeometry returns the edge polyline from the source node through the points in between to the target node.
+func EdgeGeometry(graph *datastructure.Graph, edgeID int32) orb.LineString {
+	edge := graph.GetOutEdge(edgeID)
+	from := graph.GetNode(edge.FromNodeID)
+	to := graph.GetNode(edge.ToNodeID)
+
+	line := orb.LineString{{from.Lon, from.Lat}}
+	for _, p := range graph.GraphStorage.GetPointsInbetween(edgeID) {
+		line = append(line, orb.Point{p.Lon, p.Lat})
+	}
+	return append(line, orb.Point{to.Lon, to.Lat})
+}
@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"os"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// WriteGeoJSON writes all nodes as Points and all edges as LineStrings to a GeoJSON FeatureCollection.
+func WriteGeoJSON(graph *datastructure.Graph, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := NewGeoJSONWriter(f)
+
+	for _, node := range graph.GetNodes() {
+		feature := geojson.NewFeature(orb.Point{node.Lon, node.Lat})
+		feature.Properties["type"] = "node"
+		feature.Properties["id"] = node.ID
+		feature.Properties["traffic_light"] = hasTrafficLight(graph, node.ID)
+		feature.Properties["osm_id"] = graph.GraphStorage.GetOsmNodeID(node.ID)
+		feature.Properties["synthetic"] = graph.GraphStorage.IsSyntheticNode(node.ID)
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		attr := getEdgeAttributes(graph, edgeID)
+		feature := geojson.NewFeature(EdgeGeometry(graph, edgeID))
+		feature.Properties["type"] = "edge"
+		feature.Properties["id"] = attr.edgeID
+		feature.Properties["from"] = attr.from
+		feature.Properties["to"] = attr.to
+		feature.Properties["weight"] = attr.weight
+		feature.Properties["distance"] = attr.dist
+		feature.Properties["road_class"] = attr.roadClass
+		feature.Properties["street_name"] = attr.streetName
+		feature.Properties["lanes"] = attr.lanes
+		feature.Properties["roundabout"] = attr.roundabout
+		feature.Properties["direction"] = attr.direction
+		feature.Properties["osm_way_id"] = attr.osmWayID
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+
+	return gw.Close()
+}
+
+func hasTrafficLight(graph *datastructure.Graph, nodeID int32) bool {
+	if int(nodeID/32) >= len(graph.GraphStorage.NodeTrafficLight) {
+		return false
+	}
+	return graph.GraphStorage.GetTrafficLight(nodeID)
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// GeoJSONWriter streams features as a single GeoJSON FeatureCollection, so large graphs
+// do not have to be kept in memory as one collection.
+type GeoJSONWriter struct {
+	w        *bufio.Writer
+	features int
+	err      error
+}
+
+func NewGeoJSONWriter(w io.Writer) *GeoJSONWriter {
+	gw := &GeoJSONWriter{w: bufio.NewWriterSize(w, 1<<20)}
+	_, gw.err = gw.w.WriteString(`{"type":"FeatureCollection","features":[`)
+	return gw
+}
+
+func (gw *GeoJSONWriter) Write(feature *geojson.Feature) error {
+	if gw.err != nil {
+		return gw.err
+	}
+	buf, err := json.Marshal(feature)
+	if err != nil {
+		gw.err = err
+		return err
+	}
+	if gw.features > 0 {
+		if gw.err = gw.w.WriteByte(','); gw.err != nil {
+			return gw.err
+		}
+	}
+	if gw.err = gw.w.WriteByte('\n'); gw.err != nil {
+		return gw.err
+	}
+	_, gw.err = gw.w.Write(buf)
+	gw.features++
+	return gw.err
+}
+
+// Close terminates the feature collection and flushes the underlying writer.
+func (gw *GeoJSONWriter) Close() error {
+	if gw.err != nil {
+		return gw.err
+	}
+	if _, err := gw.w.WriteString("\n]}\n"); err != nil {
+		return err
+	}
+	return gw.w.Flush()
+}
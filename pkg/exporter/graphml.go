@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+type graphMLKey struct {
+	id       string
+	forNode  bool
+	attrType string
+}
+
+var graphMLKeys = []graphMLKey{
+	{"lat", true, "double"},
+	{"lon", true, "double"},
+	{"traffic_light", true, "boolean"},
+	{"osm_id", true, "long"},
+	{"synthetic", true, "boolean"},
+	{"id", false, "int"},
+	{"weight", false, "double"},
+	{"distance", false, "double"},
+	{"road_class", false, "string"},
+	{"street_name", false, "string"},
+	{"lanes", false, "int"},
+	{"roundabout", false, "boolean"},
+	{"direction", false, "string"},
+	{"osm_way_id", false, "long"},
+}
+
+// WriteGraphML writes the graph as a directed GraphML graph. bidirectional edges are written once
+// with direction "bidirectional", so networkx users should add the reverse edge for those.
+func WriteGraphML(graph *datastructure.Graph, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriterSize(f, 1<<20)
+
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	for _, key := range graphMLKeys {
+		target := "edge"
+		if key.forNode {
+			target = "node"
+		}
+		fmt.Fprintf(w, "  <key id=\"%s_%s\" for=\"%s\" attr.name=\"%s\" attr.type=\"%s\"/>\n", target, key.id, target, key.id, key.attrType)
+	}
+	fmt.Fprintln(w, `  <graph id="G" edgedefault="directed">`)
+
+	for _, node := range graph.GetNodes() {
+		fmt.Fprintf(w, "    <node id=\"n%d\">", node.ID)
+		writeGraphMLData(w, "node_lat", strconv.FormatFloat(node.Lat, 'f', -1, 64))
+		writeGraphMLData(w, "node_lon", strconv.FormatFloat(node.Lon, 'f', -1, 64))
+		writeGraphMLData(w, "node_traffic_light", strconv.FormatBool(hasTrafficLight(graph, node.ID)))
+		writeGraphMLData(w, "node_osm_id", strconv.FormatInt(graph.GraphStorage.GetOsmNodeID(node.ID), 10))
+		writeGraphMLData(w, "node_synthetic", strconv.FormatBool(graph.GraphStorage.IsSyntheticNode(node.ID)))
+		fmt.Fprintln(w, "</node>")
+	}
+
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		attr := getEdgeAttributes(graph, edgeID)
+		fmt.Fprintf(w, "    <edge id=\"e%d\" source=\"n%d\" target=\"n%d\">", attr.edgeID, attr.from, attr.to)
+		writeGraphMLData(w, "edge_id", strconv.Itoa(int(attr.edgeID)))
+		writeGraphMLData(w, "edge_weight", strconv.FormatFloat(attr.weight, 'f', -1, 64))
+		writeGraphMLData(w, "edge_distance", strconv.FormatFloat(attr.dist, 'f', -1, 64))
+		writeGraphMLData(w, "edge_road_class", attr.roadClass)
+		writeGraphMLData(w, "edge_street_name", attr.streetName)
+		writeGraphMLData(w, "edge_lanes", strconv.Itoa(int(attr.lanes)))
+		writeGraphMLData(w, "edge_roundabout", strconv.FormatBool(attr.roundabout))
+		writeGraphMLData(w, "edge_direction", attr.direction)
+		writeGraphMLData(w, "edge_osm_way_id", strconv.FormatInt(attr.osmWayID, 10))
+		fmt.Fprintln(w, "</edge>")
+	}
+
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</graphml>")
+	return w.Flush()
+}
+
+func writeGraphMLData(w *bufio.Writer, key, value string) {
+	fmt.Fprintf(w, "<data key=\"%s\">", key)
+	xml.EscapeText(w, []byte(value))
+	w.WriteString("</data>")
+}
@@ -0,0 +1,85 @@
+package flow
+
+// Dinic computes a maximum flow from the sources to the sinks with dinic's algorithm: blocking flows on the
+// bfs level graph of the residual network. returns the flow value, the flow and the minimum cut are kept in the network.
+// nodes that are both a source and a sink only count as sinks.
+func (nw *Network) Dinic(sources, sinks []int32) int64 {
+	nw.prepare(sources, sinks)
+	return nw.dinic(sources)
+}
+
+// ContinueDinic augments the flow of the last max flow computation to a maximum flow from sources to sinks,
+// which must contain the previous sources and sinks, and no arcs may have been added since. returns the additional flow.
+func (nw *Network) ContinueDinic(sources, sinks []int32) int64 {
+	nw.markTerminals(sources, sinks)
+	return nw.dinic(sources)
+}
+
+func (nw *Network) dinic(sources []int32) int64 {
+	flow := int64(0)
+	for nw.levelGraph() {
+		copy(nw.current, nw.firstArc[:nw.nodeCount])
+		for _, s := range sources {
+			if !nw.isSource[s] {
+				continue
+			}
+			for {
+				pushed := nw.augment(s, Infinity)
+				if pushed == 0 {
+					break
+				}
+				flow += pushed
+			}
+		}
+	}
+	return flow
+}
+
+// levelGraph labels every node with its bfs distance from the sources in the residual network,
+// returns true if a sink is reachable.
+func (nw *Network) levelGraph() bool {
+	nw.queue = nw.queue[:0]
+	for u := 0; u < nw.nodeCount; u++ {
+		nw.label[u] = -1
+		if nw.isSource[u] {
+			nw.label[u] = 0
+			nw.queue = append(nw.queue, int32(u))
+		}
+	}
+
+	reachedSink := false
+	for i := 0; i < len(nw.queue); i++ {
+		u := nw.queue[i]
+		if nw.isSink[u] {
+			reachedSink = true
+			continue
+		}
+		for _, arc := range nw.arcsOf[nw.firstArc[u]:nw.firstArc[u+1]] {
+			if v := nw.head[arc]; nw.residual[arc] > 0 && nw.label[v] == -1 {
+				nw.label[v] = nw.label[u] + 1
+				nw.queue = append(nw.queue, v)
+			}
+		}
+	}
+	return reachedSink
+}
+
+// augment pushes up to limit units of flow from u to a sink along the level graph, returns the pushed amount.
+func (nw *Network) augment(u int32, limit int64) int64 {
+	if nw.isSink[u] {
+		return limit
+	}
+	for ; nw.current[u] < nw.firstArc[u+1]; nw.current[u]++ {
+		arc := nw.arcsOf[nw.current[u]]
+		v := nw.head[arc]
+		if nw.residual[arc] == 0 || nw.label[v] != nw.label[u]+1 {
+			continue
+		}
+		if pushed := nw.augment(v, min(limit, nw.residual[arc])); pushed > 0 {
+			nw.residual[arc] -= pushed
+			nw.residual[arc^1] += pushed
+			return pushed
+		}
+	}
+	return 0
+}
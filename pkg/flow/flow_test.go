@@ -0,0 +1,192 @@
+package flow
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"golang.org/x/exp/rand"
+)
+
+// randomNetwork resets nw to a random network with up to 8 nodes and up to 3 sources and 3 sinks,
+// sources and sinks overlap if overlap is set.
+func randomNetwork(nw *Network, rng *rand.Rand, overlap bool) (sources, sinks []int32) {
+	n := 2 + rng.Intn(7)
+	nw.Reset(n)
+	arcs := rng.Intn(3 * n)
+	for i := 0; i < arcs; i++ {
+		u, v := int32(rng.Intn(n)), int32(rng.Intn(n))
+		if u == v {
+			continue
+		}
+		nw.AddEdge(u, v, int64(rng.Intn(6)), int64(rng.Intn(3)))
+	}
+
+	perm := rng.Perm(n)
+	sourceCount := 1 + rng.Intn(min(3, n-1))
+	sinkCount := 1 + rng.Intn(min(3, n-sourceCount))
+	for _, u := range perm[:sourceCount] {
+		sources = append(sources, int32(u))
+	}
+	for _, u := range perm[sourceCount : sourceCount+sinkCount] {
+		sinks = append(sinks, int32(u))
+	}
+	if overlap {
+		sinks = append(sinks, sources[0])
+	}
+	return sources, sinks
+}
+
+// bruteForceMinCut enumerates every source side that contains the sources and no sink and returns the smallest
+// capacity of the arcs leaving it. nodes that are both a source and a sink count as sinks.
+func bruteForceMinCut(nw *Network, sources, sinks []int32) int64 {
+	n := nw.GetNodeCount()
+	isSink := make([]bool, n)
+	for _, t := range sinks {
+		isSink[t] = true
+	}
+	required, forbidden := 0, 0
+	for _, s := range sources {
+		if !isSink[s] {
+			required |= 1 << s
+		}
+	}
+	for _, t := range sinks {
+		forbidden |= 1 << t
+	}
+	if required == 0 {
+		return 0
+	}
+
+	best := int64(Infinity)
+	for side := 0; side < 1<<n; side++ {
+		if side&required != required || side&forbidden != 0 {
+			continue
+		}
+		best = min(best, cutCapacity(nw, func(u int32) bool { return side&(1<<u) != 0 }))
+	}
+	return best
+}
+
+// cutCapacity returns the capacity of the arcs from inSide to the rest.
+func cutCapacity(nw *Network, inSide func(u int32) bool) int64 {
+	capacity := int64(0)
+	for arc := int32(0); arc < int32(len(nw.head)); arc++ {
+		if inSide(nw.GetTail(arc)) && !inSide(nw.GetHead(arc)) {
+			capacity += nw.capacity[arc]
+		}
+	}
+	return capacity
+}
+
+// checkMinCut checks the minimum cut kept in the network after a max flow computation with the given value.
+func checkMinCut(t *testing.T, nw *Network, flow int64, name string, test int) {
+	t.Helper()
+	sourceSide := nw.SourceSide()
+	if capacity := cutCapacity(nw, func(u int32) bool { return sourceSide[u] }); capacity != flow {
+		t.Errorf("test %d: %s source side cut capacity %d, flow %d", test, name, capacity, flow)
+	}
+	capacity := int64(0)
+	for _, arc := range nw.CutArcs() {
+		capacity += nw.capacity[arc]
+	}
+	if capacity != flow {
+		t.Errorf("test %d: %s cut arcs capacity %d, flow %d", test, name, capacity, flow)
+	}
+}
+
+func testAgainstBruteForce(t *testing.T, overlap bool) {
+	rng := rand.New(rand.NewSource(1))
+	nw := NewNetwork(0) // reused by every test through Reset
+	for test := 0; test < 2000; test++ {
+		sources, sinks := randomNetwork(nw, rng, overlap)
+		want := bruteForceMinCut(nw, sources, sinks)
+
+		if got := nw.Dinic(sources, sinks); got != want {
+			t.Fatalf("test %d: dinic flow %d, brute force min cut %d", test, got, want)
+		}
+		checkMinCut(t, nw, want, "dinic", test)
+
+		if got := nw.PushRelabel(sources, sinks); got != want {
+			t.Fatalf("test %d: push-relabel flow %d, brute force min cut %d", test, got, want)
+		}
+		checkMinCut(t, nw, want, "push-relabel", test)
+	}
+}
+
+func TestMaxFlowBruteForce(t *testing.T) {
+	testAgainstBruteForce(t, false)
+}
+
+func TestMaxFlowOverlappingTerminals(t *testing.T) {
+	testAgainstBruteForce(t, true)
+}
+
+func TestContinueDinic(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	nw := NewNetwork(0)
+	for test := 0; test < 1000; test++ {
+		sources, sinks := randomNetwork(nw, rng, false)
+		flow := nw.Dinic(sources[:1], sinks[:1])
+		flow += nw.ContinueDinic(sources, sinks)
+		if want := bruteForceMinCut(nw, sources, sinks); flow != want {
+			t.Fatalf("test %d: continued dinic flow %d, brute force min cut %d", test, flow, want)
+		}
+		checkMinCut(t, nw, flow, "continued dinic", test)
+	}
+}
+
+// benchmarkNetwork builds the network of a bfs ball of up to 20000 nodes of the graph in NAVIGATORX_BENCH_GRAPH,
+// a binary graph file written by the -g flag, e.g. of a city like solo_jogja.osm.pbf. the first tenth of the ball are
+// the sources, the last tenth the sinks.
+func benchmarkNetwork(b *testing.B) (*Network, []int32, []int32) {
+	filename := os.Getenv("NAVIGATORX_BENCH_GRAPH")
+	if filename == "" {
+		b.Skip("NAVIGATORX_BENCH_GRAPH is not set")
+	}
+	graph, err := datastructure.ReadGraphFromFile(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	visited := make([]bool, graph.GetNodeCount())
+	ball := []int32{int32(graph.GetNodeCount() / 2)}
+	visited[ball[0]] = true
+	for head := 0; head < len(ball) && len(ball) < 20000; head++ {
+		graph.ForOutEdges(ball[head], func(edge datastructure.Edge) {
+			if !visited[edge.ToNodeID] && len(ball) < 20000 {
+				visited[edge.ToNodeID] = true
+				ball = append(ball, edge.ToNodeID)
+			}
+		})
+	}
+
+	nw := NewNetwork(0)
+	nw.BuildSubgraph(graph, ball, func(edge datastructure.Edge) int64 { return 1 })
+	sources, sinks := make([]int32, 0), make([]int32, 0)
+	for i := range ball {
+		switch {
+		case i < len(ball)/10:
+			sources = append(sources, int32(i))
+		case i >= len(ball)-len(ball)/10:
+			sinks = append(sinks, int32(i))
+		}
+	}
+	return nw, sources, sinks
+}
+
+func BenchmarkDinic(b *testing.B) {
+	nw, sources, sinks := benchmarkNetwork(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nw.Dinic(sources, sinks)
+	}
+}
+
+func BenchmarkPushRelabel(b *testing.B) {
+	nw, sources, sinks := benchmarkNetwork(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nw.PushRelabel(sources, sinks)
+	}
+}
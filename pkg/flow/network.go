@@ -0,0 +1,202 @@
+package flow
+
+import (
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// Infinity is the capacity of arcs that must never be cut.
+const Infinity = math.MaxInt64 / 4
+
+// Network is a directed flow network with int64 capacities. every arc is stored with its reverse arc, arc a^1 is the reverse of a.
+// the buffers of a network are reused by Reset, so one network can solve many small max flow problems without allocating.
+// a Network must not be used by multiple goroutines at the same time.
+type Network struct {
+	nodeCount int
+
+	head     []int32
+	capacity []int64
+	residual []int64
+
+	// arcs leaving node u are arcsOf[firstArc[u]:firstArc[u+1]], rebuilt by build after arcs were added
+	firstArc []int32
+	arcsOf   []int32
+	built    bool
+
+	isSource []bool
+	isSink   []bool
+	excess   []int64
+	label    []int32
+	current  []int32 // next arc of every node to try, for dinic and push-relabel
+	queue    []int32
+}
+
+func NewNetwork(nodeCount int) *Network {
+	nw := &Network{}
+	nw.Reset(nodeCount)
+	return nw
+}
+
+// Reset removes every arc and resizes the network to nodeCount nodes.
+func (nw *Network) Reset(nodeCount int) {
+	nw.nodeCount = nodeCount
+	nw.head = nw.head[:0]
+	nw.capacity = nw.capacity[:0]
+	nw.residual = nw.residual[:0]
+	nw.built = false
+
+	nw.isSource = resize(nw.isSource, nodeCount)
+	nw.isSink = resize(nw.isSink, nodeCount)
+	nw.excess = resize(nw.excess, nodeCount)
+	nw.label = resize(nw.label, nodeCount)
+	nw.current = resize(nw.current, nodeCount)
+	nw.firstArc = resize(nw.firstArc, nodeCount+1)
+}
+
+func resize[T any](arr []T, n int) []T {
+	if cap(arr) < n {
+		return make([]T, n)
+	}
+	return arr[:n]
+}
+
+func (nw *Network) GetNodeCount() int {
+	return nw.nodeCount
+}
+
+// AddEdge adds the arc u->v with capacity and its reverse arc v->u with reverseCapacity, returns the id of the arc u->v.
+func (nw *Network) AddEdge(u, v int32, capacity, reverseCapacity int64) int32 {
+	arc := int32(len(nw.head))
+	nw.head = append(nw.head, v, u)
+	nw.capacity = append(nw.capacity, capacity, reverseCapacity)
+	nw.residual = append(nw.residual, capacity, reverseCapacity)
+	nw.built = false
+	return arc
+}
+
+// BuildSubgraph resets the network to the subgraph of graph induced by nodeIds, network node i is nodeIds[i].
+// every edge that can be traversed from u to v inside the subgraph becomes an arc u->v with capacity(edge),
+// so bidirectional edges become an arc in both directions.
+func (nw *Network) BuildSubgraph(graph *datastructure.Graph, nodeIds []int32, capacity func(edge datastructure.Edge) int64) {
+	nw.Reset(len(nodeIds))
+	localId := make(map[int32]int32, len(nodeIds))
+	for i, nodeId := range nodeIds {
+		localId[nodeId] = int32(i)
+	}
+	for i, nodeId := range nodeIds {
+		graph.ForOutEdges(nodeId, func(edge datastructure.Edge) {
+			if v, ok := localId[edge.ToNodeID]; ok && v != int32(i) {
+				nw.AddEdge(int32(i), v, capacity(edge), 0)
+			}
+		})
+	}
+}
+
+// GetHead returns the node the arc points to.
+func (nw *Network) GetHead(arc int32) int32 {
+	return nw.head[arc]
+}
+
+// GetTail returns the node the arc leaves.
+func (nw *Network) GetTail(arc int32) int32 {
+	return nw.head[arc^1]
+}
+
+// GetArcs returns the arcs leaving u, including the reverse arcs. only valid after a max flow computation.
+func (nw *Network) GetArcs(u int32) []int32 {
+	return nw.arcsOf[nw.firstArc[u]:nw.firstArc[u+1]]
+}
+
+// GetResidual returns the residual capacity of the arc after the last max flow.
+func (nw *Network) GetResidual(arc int32) int64 {
+	return nw.residual[arc]
+}
+
+// GetFlow returns the flow on the arc computed by the last max flow, negative if flow goes through the reverse arc.
+func (nw *Network) GetFlow(arc int32) int64 {
+	return nw.capacity[arc] - nw.residual[arc]
+}
+
+// build sorts the arcs by tail.
+func (nw *Network) build() {
+	if nw.built {
+		return
+	}
+	for i := range nw.firstArc {
+		nw.firstArc[i] = 0
+	}
+	for arc := range nw.head {
+		nw.firstArc[nw.head[arc^1]+1]++
+	}
+	for u := 0; u < nw.nodeCount; u++ {
+		nw.firstArc[u+1] += nw.firstArc[u]
+	}
+	nw.arcsOf = resize(nw.arcsOf, len(nw.head))
+	copy(nw.current, nw.firstArc[:nw.nodeCount])
+	for arc := range nw.head {
+		tail := nw.head[arc^1]
+		nw.arcsOf[nw.current[tail]] = int32(arc)
+		nw.current[tail]++
+	}
+	nw.built = true
+}
+
+// prepare resets the flow and marks the sources and sinks before a max flow computation.
+func (nw *Network) prepare(sources, sinks []int32) {
+	nw.build()
+	copy(nw.residual, nw.capacity)
+	for u := 0; u < nw.nodeCount; u++ {
+		nw.isSource[u] = false
+		nw.isSink[u] = false
+		nw.excess[u] = 0
+	}
+	nw.markTerminals(sources, sinks)
+}
+
+// markTerminals marks the sources and sinks. a node that is both a source and a sink only counts as a sink,
+// otherwise it could send unlimited flow to itself.
+func (nw *Network) markTerminals(sources, sinks []int32) {
+	for _, t := range sinks {
+		nw.isSink[t] = true
+		nw.isSource[t] = false
+	}
+	for _, s := range sources {
+		nw.isSource[s] = !nw.isSink[s]
+	}
+}
+
+// SourceSide returns the nodes reachable from a source in the residual network of the last max flow,
+// the smallest source side of a minimum cut.
+func (nw *Network) SourceSide() []bool {
+	reachable := make([]bool, nw.nodeCount)
+	nw.queue = nw.queue[:0]
+	for u := 0; u < nw.nodeCount; u++ {
+		if nw.isSource[u] {
+			reachable[u] = true
+			nw.queue = append(nw.queue, int32(u))
+		}
+	}
+	for i := 0; i < len(nw.queue); i++ {
+		u := nw.queue[i]
+		for _, arc := range nw.arcsOf[nw.firstArc[u]:nw.firstArc[u+1]] {
+			if v := nw.head[arc]; nw.residual[arc] > 0 && !reachable[v] {
+				reachable[v] = true
+				nw.queue = append(nw.queue, v)
+			}
+		}
+	}
+	return reachable
+}
+
+// CutArcs returns the arcs with positive capacity from the source side to the rest, their capacities sum up to the max flow.
+func (nw *Network) CutArcs() []int32 {
+	sourceSide := nw.SourceSide()
+	cut := make([]int32, 0)
+	for arc := int32(0); arc < int32(len(nw.head)); arc++ {
+		if nw.capacity[arc] > 0 && sourceSide[nw.head[arc^1]] && !sourceSide[nw.head[arc]] {
+			cut = append(cut, arc)
+		}
+	}
+	return cut
+}
@@ -0,0 +1,137 @@
+package flow
+
+// PushRelabel computes a maximum flow from the sources to the sinks with the highest label push-relabel algorithm
+// and global relabeling. the first phase pushes a maximum preflow into the sinks, the second phase returns the excess
+// that cannot reach a sink to the sources, so that the network holds a valid flow afterwards. returns the flow value.
+// nodes that are both a source and a sink only count as sinks.
+func (nw *Network) PushRelabel(sources, sinks []int32) int64 {
+	nw.prepare(sources, sinks)
+
+	for _, s := range sources {
+		if !nw.isSource[s] {
+			continue
+		}
+		for _, arc := range nw.arcsOf[nw.firstArc[s]:nw.firstArc[s+1]] {
+			if v := nw.head[arc]; nw.residual[arc] > 0 && !nw.isSource[v] {
+				nw.push(arc, nw.residual[arc])
+			}
+		}
+	}
+
+	nw.discharge(nw.isSink, nw.isSource)
+	flow := int64(0)
+	for u := 0; u < nw.nodeCount; u++ {
+		if nw.isSink[u] {
+			flow += nw.excess[u]
+		}
+	}
+	nw.discharge(nw.isSource, nw.isSink)
+	return flow
+}
+
+func (nw *Network) push(arc int32, amount int64) {
+	nw.residual[arc] -= amount
+	nw.residual[arc^1] += amount
+	nw.excess[nw.head[arc^1]] -= amount
+	nw.excess[nw.head[arc]] += amount
+}
+
+// discharge pushes the excess of every node that is neither a target nor ignored towards the targets, as long as a target
+// can be reached in the residual network. labels are distances to the targets, nodeCount means unreachable.
+func (nw *Network) discharge(isTarget, isIgnored []bool) {
+	n := int32(nw.nodeCount)
+	buckets := make([][]int32, n)
+	highest := int32(-1)
+	activate := func(u int32) {
+		if nw.label[u] < n && !isTarget[u] && !isIgnored[u] {
+			buckets[nw.label[u]] = append(buckets[nw.label[u]], u)
+			highest = max(highest, nw.label[u])
+		}
+	}
+
+	// globalRelabel sets the labels to the exact residual distances to the targets and rebuilds the buckets.
+	globalRelabel := func() {
+		nw.queue = nw.queue[:0]
+		for u := int32(0); u < n; u++ {
+			nw.label[u] = n
+			if isTarget[u] {
+				nw.label[u] = 0
+				nw.queue = append(nw.queue, u)
+			}
+		}
+		for i := 0; i < len(nw.queue); i++ {
+			v := nw.queue[i]
+			for _, arc := range nw.arcsOf[nw.firstArc[v]:nw.firstArc[v+1]] {
+				u := nw.head[arc]
+				if nw.residual[arc^1] > 0 && nw.label[u] == n && !isIgnored[u] {
+					nw.label[u] = nw.label[v] + 1
+					nw.queue = append(nw.queue, u)
+				}
+			}
+		}
+
+		for i := range buckets {
+			buckets[i] = buckets[i][:0]
+		}
+		highest = -1
+		copy(nw.current, nw.firstArc[:nw.nodeCount])
+		for u := int32(0); u < n; u++ {
+			if nw.excess[u] > 0 {
+				activate(u)
+			}
+		}
+	}
+
+	globalRelabel()
+	relabels := int32(0)
+	for highest >= 0 {
+		bucket := buckets[highest]
+		if len(bucket) == 0 {
+			highest--
+			continue
+		}
+		u := bucket[len(bucket)-1]
+		buckets[highest] = bucket[:len(bucket)-1]
+		if nw.excess[u] == 0 || nw.label[u] != highest {
+			continue
+		}
+
+		for nw.excess[u] > 0 {
+			if nw.current[u] == nw.firstArc[u+1] {
+				// relabel to one more than the lowest neighbor reachable in the residual network
+				label := n
+				for _, arc := range nw.arcsOf[nw.firstArc[u]:nw.firstArc[u+1]] {
+					if v := nw.head[arc]; nw.residual[arc] > 0 && !isIgnored[v] {
+						label = min(label, nw.label[v]+1)
+					}
+				}
+				nw.label[u] = label
+				nw.current[u] = nw.firstArc[u]
+				relabels++
+				if label >= n {
+					break
+				}
+				continue
+			}
+
+			arc := nw.arcsOf[nw.current[u]]
+			v := nw.head[arc]
+			if nw.residual[arc] > 0 && nw.label[u] == nw.label[v]+1 && !isIgnored[v] {
+				wasActive := nw.excess[v] > 0
+				nw.push(arc, min(nw.excess[u], nw.residual[arc]))
+				if !wasActive {
+					activate(v)
+				}
+				continue
+			}
+			nw.current[u]++
+		}
+
+		if relabels >= n {
+			relabels = 0
+			globalRelabel()
+		} else if nw.excess[u] > 0 {
+			activate(u)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package geo
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+/*
+CellPolygons computes one multipolygon per label as the union of the (discrete) voronoi regions of the points with that label.
+
+the bounding box of the points is rasterized into a grid of at most gridSize x gridSize squares. every square is assigned the label
+of its nearest point with a multi source bfs (chebyshev distance), squares farther than maxDistance meters from every point stay empty,
+so cells do not grow into areas without roads. the boundary of each label's squares is then traced into rings:
+counter clockwise rings are outer rings, clockwise rings are holes.
+a label whose points all share their squares with points of other labels (a cell much smaller than a square) gets no square,
+its polygon is the convex hull of its points instead, padded to half a square if the points are collinear.
+
+points[i] has label labels[i], labels must be in [0, numLabels) or negative to ignore the point.
+the result only depends on the input order, so equal inputs produce equal polygons.
+*/
+func CellPolygons(points []datastructure.Coordinate, labels []int32, numLabels int, gridSize int,
+	maxDistance float64) []orb.MultiPolygon {
+	polygons := make([]orb.MultiPolygon, numLabels)
+	if len(points) == 0 || numLabels == 0 {
+		return polygons
+	}
+
+	minLat, minLon := math.MaxFloat64, math.MaxFloat64
+	maxLat, maxLon := -math.MaxFloat64, -math.MaxFloat64
+	for _, p := range points {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+	}
+
+	step := math.Max(maxLat-minLat, maxLon-minLon) / float64(gridSize)
+	if step == 0 {
+		step = 1e-4
+	}
+	stepMeters := CalculateHaversineDistance(minLat, minLon, minLat+step, minLon) * 1000
+	maxSteps := int32(math.Ceil(maxDistance / stepMeters))
+
+	// pad the grid by maxSteps squares on every side so regions at the border are closed.
+	pad := int(maxSteps) + 1
+	minLat -= float64(pad) * step
+	minLon -= float64(pad) * step
+	grid := newLabelGrid(
+		int(math.Ceil((maxLon-minLon)/step))+pad+1,
+		int(math.Ceil((maxLat-minLat)/step))+pad+1,
+	)
+
+	queue := make([]int32, 0, len(points))
+	for i, p := range points {
+		if labels[i] < 0 {
+			continue
+		}
+		x := int((p.Lon - minLon) / step)
+		y := int((p.Lat - minLat) / step)
+		sq := grid.index(x, y)
+		if grid.labels[sq] == -1 {
+			grid.labels[sq] = labels[i]
+			grid.dist[sq] = 0
+			queue = append(queue, sq)
+		}
+	}
+
+	// multi source bfs over the 8-neighborhood
+	for head := 0; head < len(queue); head++ {
+		sq := queue[head]
+		if grid.dist[sq] >= maxSteps {
+			continue
+		}
+		x, y := grid.coord(sq)
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if (dx == 0 && dy == 0) || !grid.inside(nx, ny) {
+					continue
+				}
+				next := grid.index(nx, ny)
+				if grid.labels[next] != -1 {
+					continue
+				}
+				grid.labels[next] = grid.labels[sq]
+				grid.dist[next] = grid.dist[sq] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	toPoint := func(v gridVertex) orb.Point {
+		return orb.Point{minLon + float64(v.x)*step, minLat + float64(v.y)*step}
+	}
+
+	for label, rings := range grid.traceBoundaries(numLabels) {
+		polygons[label] = assembleMultiPolygon(rings, toPoint)
+	}
+
+	hullPoints := make(map[int32][]orb.Point)
+	for i, p := range points {
+		if label := labels[i]; label >= 0 && len(polygons[label]) == 0 {
+			hullPoints[label] = append(hullPoints[label], orb.Point{p.Lon, p.Lat})
+		}
+	}
+	for label, labelPoints := range hullPoints {
+		polygons[label] = orb.MultiPolygon{hullPolygon(labelPoints, step/2)}
+	}
+	return polygons
+}
+
+// hullPolygon returns the convex hull of the points as a counter clockwise polygon, or the bound of the points
+// padded by pad if the hull has no area.
+func hullPolygon(points []orb.Point, pad float64) orb.Polygon {
+	sorted := append([]orb.Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+	cross := func(o, a, b orb.Point) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	// andrew's monotone chain, the lower hull from left to right then the upper hull from right to left
+	hull := make(orb.Ring, 0, 2*len(sorted))
+	for _, half := range []int{0, 1} {
+		start := len(hull)
+		for k := range sorted {
+			p := sorted[k]
+			if half == 1 {
+				p = sorted[len(sorted)-1-k]
+			}
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1] // the last point starts the other half
+	}
+
+	if len(hull) < 3 {
+		bound := orb.MultiPoint(points).Bound()
+		return bound.Pad(pad).ToPolygon()
+	}
+	return orb.Polygon{append(hull, hull[0])}
+}
+
+type labelGrid struct {
+	width  int
+	height int
+	labels []int32
+	dist   []int32
+}
+
+func newLabelGrid(width, height int) *labelGrid {
+	g := &labelGrid{
+		width:  width,
+		height: height,
+		labels: make([]int32, width*height),
+		dist:   make([]int32, width*height),
+	}
+	for i := range g.labels {
+		g.labels[i] = -1
+	}
+	return g
+}
+
+func (g *labelGrid) index(x, y int) int32 {
+	return int32(y*g.width + x)
+}
+
+func (g *labelGrid) coord(sq int32) (int, int) {
+	return int(sq) % g.width, int(sq) / g.width
+}
+
+func (g *labelGrid) inside(x, y int) bool {
+	return x >= 0 && y >= 0 && x < g.width && y < g.height
+}
+
+func (g *labelGrid) labelAt(x, y int) int32 {
+	if !g.inside(x, y) {
+		return -1
+	}
+	return g.labels[g.index(x, y)]
+}
+
+type gridVertex struct {
+	x, y int32
+}
+
+type gridEdge struct {
+	from, to gridVertex
+}
+
+// traceBoundaries returns the closed boundary rings of every label. the region is always on the left of a ring,
+// so outer rings are counter clockwise and holes are clockwise.
+func (g *labelGrid) traceBoundaries(numLabels int) [][][]gridVertex {
+	boundaryEdges := make([][]gridEdge, numLabels)
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			label := g.labelAt(x, y)
+			if label < 0 {
+				continue
+			}
+			x0, y0, x1, y1 := int32(x), int32(y), int32(x+1), int32(y+1)
+			if g.labelAt(x, y-1) != label {
+				boundaryEdges[label] = append(boundaryEdges[label], gridEdge{gridVertex{x0, y0}, gridVertex{x1, y0}})
+			}
+			if g.labelAt(x+1, y) != label {
+				boundaryEdges[label] = append(boundaryEdges[label], gridEdge{gridVertex{x1, y0}, gridVertex{x1, y1}})
+			}
+			if g.labelAt(x, y+1) != label {
+				boundaryEdges[label] = append(boundaryEdges[label], gridEdge{gridVertex{x1, y1}, gridVertex{x0, y1}})
+			}
+			if g.labelAt(x-1, y) != label {
+				boundaryEdges[label] = append(boundaryEdges[label], gridEdge{gridVertex{x0, y1}, gridVertex{x0, y0}})
+			}
+		}
+	}
+
+	rings := make([][][]gridVertex, numLabels)
+	for label, edges := range boundaryEdges {
+		rings[label] = chainEdges(edges)
+	}
+	return rings
+}
+
+// chainEdges links directed boundary edges into closed rings. at a vertex where two squares of the region only touch
+// diagonally there are two outgoing edges, we always take the left turn so the two squares end up in separate rings.
+func chainEdges(edges []gridEdge) [][]gridVertex {
+	outgoing := make(map[gridVertex][]int, len(edges))
+	for i, e := range edges {
+		outgoing[e.from] = append(outgoing[e.from], i)
+	}
+	used := make([]bool, len(edges))
+
+	rings := make([][]gridVertex, 0)
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+		ring := []gridVertex{edges[start].from}
+		used[start] = true
+		curr := edges[start]
+		for curr.to != edges[start].from {
+			ring = append(ring, curr.to)
+			dx, dy := curr.to.x-curr.from.x, curr.to.y-curr.from.y
+
+			next := -1
+			for _, candidate := range outgoing[curr.to] {
+				if used[candidate] {
+					continue
+				}
+				cdx, cdy := edges[candidate].to.x-edges[candidate].from.x, edges[candidate].to.y-edges[candidate].from.y
+				if next == -1 || (cdx == -dy && cdy == dx) {
+					next = candidate
+				}
+			}
+			if next == -1 {
+				// unreachable for boundaries of a grid region, every vertex has as many incoming as outgoing edges.
+				break
+			}
+			used[next] = true
+			curr = edges[next]
+		}
+		rings = append(rings, removeCollinear(ring))
+	}
+	return rings
+}
+
+// removeCollinear drops ring vertices where the boundary does not change direction.
+func removeCollinear(ring []gridVertex) []gridVertex {
+	n := len(ring)
+	simplified := make([]gridVertex, 0, n)
+	for i := 0; i < n; i++ {
+		prev, curr, next := ring[(i+n-1)%n], ring[i], ring[(i+1)%n]
+		if (curr.x-prev.x)*(next.y-curr.y)-(curr.y-prev.y)*(next.x-curr.x) != 0 {
+			simplified = append(simplified, curr)
+		}
+	}
+	return simplified
+}
+
+// assembleMultiPolygon turns the rings of one label into polygons, every hole is added to the smallest outer ring containing it.
+func assembleMultiPolygon(rings [][]gridVertex, toPoint func(gridVertex) orb.Point) orb.MultiPolygon {
+	var (
+		outers []orb.Ring
+		holes  []orb.Ring
+	)
+	for _, ring := range rings {
+		if len(ring) < 3 {
+			continue
+		}
+		r := make(orb.Ring, 0, len(ring)+1)
+		for _, v := range ring {
+			r = append(r, toPoint(v))
+		}
+		r = append(r, r[0])
+		if r.Orientation() == orb.CCW {
+			outers = append(outers, r)
+		} else {
+			holes = append(holes, r)
+		}
+	}
+
+	multiPolygon := make(orb.MultiPolygon, len(outers))
+	areas := make([]float64, len(outers))
+	for i, outer := range outers {
+		multiPolygon[i] = orb.Polygon{outer}
+		areas[i] = math.Abs(planar.Area(outer))
+	}
+
+	for _, hole := range holes {
+		// the midpoint of the first hole segment is never on the boundary of another ring of the same label.
+		probe := orb.Point{(hole[0][0] + hole[1][0]) / 2, (hole[0][1] + hole[1][1]) / 2}
+		best := -1
+		for i, outer := range outers {
+			if planar.RingContains(outer, probe) && (best == -1 || areas[i] < areas[best]) {
+				best = i
+			}
+		}
+		if best != -1 {
+			multiPolygon[best] = append(multiPolygon[best], hole)
+		}
+	}
+	return multiPolygon
+}
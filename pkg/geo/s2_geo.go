@@ -22,7 +22,7 @@ func ProjectPointToLineCoord(nearestStPoint datastructure.Coordinate, secondNear
 	snapS2 := s2.PointFromLatLng(s2.LatLngFromDegrees(snapLat, snapLon))
 	projection := s2.Project(snapS2, nearestStS2, secondNearestStS2)
 	projectLatLng := s2.LatLngFromPoint(projection)
-	return datastructure.Coordinate{projectLatLng.Lat.Degrees(), projectLatLng.Lng.Degrees()}
+	return datastructure.NewCoordinate(projectLatLng.Lat.Degrees(), projectLatLng.Lng.Degrees())
 }
 
 // return in meter
@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/geo"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+// graphBuilder collects nodes and edges read from a file and turns them into a datastructure.Graph
+// with the same storage layout the osm parser produces.
+type graphBuilder struct {
+	nodes        []datastructure.CHNode
+	graphStorage *datastructure.GraphStorage
+	tagStringMap util.IDMap
+	hasCoords    bool
+}
+
+func newGraphBuilder(nodeCount int) *graphBuilder {
+	gb := &graphBuilder{
+		nodes:        make([]datastructure.CHNode, nodeCount),
+		graphStorage: datastructure.NewGraphStorage(),
+		tagStringMap: util.NewIdMap(),
+	}
+	for i := range gb.nodes {
+		gb.nodes[i] = datastructure.NewCHNode(0, 0, int32(i), int32(i))
+	}
+	gb.tagStringMap.GetID("") // empty street name & road class
+	return gb
+}
+
+func (gb *graphBuilder) addNode() int32 {
+	id := int32(len(gb.nodes))
+	gb.nodes = append(gb.nodes, datastructure.NewCHNode(0, 0, id, id))
+	return id
+}
+
+func (gb *graphBuilder) setCoordinate(nodeID int32, lat, lon float64) {
+	gb.nodes[nodeID].Lat = lat
+	gb.nodes[nodeID].Lon = lon
+	gb.hasCoords = true
+}
+
+// addEdge adds an edge from -> to. if dist is negative it is computed from the node coordinates.
+func (gb *graphBuilder) addEdge(from, to int32, weight, dist float64, directed bool) {
+	gs := gb.graphStorage
+	edgeID := int32(len(gs.EdgeStorage))
+
+	gs.AppendMapEdgeInfo(datastructure.NewEdgeExtraInfo(0, 0, 1, 0,
+		uint32(len(gs.GlobalPoints)), uint32(len(gs.GlobalPoints)), 0))
+	gs.SetRoundabout(edgeID, false)
+	gs.AppendEdgeStorage(datastructure.NewEdge(edgeID, to, from, -1, weight, dist, directed))
+}
+
+func (gb *graphBuilder) build() (*datastructure.Graph, error) {
+	gs := gb.graphStorage
+	for i, edge := range gs.EdgeStorage {
+		if edge.Dist >= 0 {
+			continue
+		}
+		if !gb.hasCoords {
+			gs.EdgeStorage[i].Dist = edge.Weight
+			continue
+		}
+		from, to := gb.nodes[edge.FromNodeID], gb.nodes[edge.ToNodeID]
+		gs.EdgeStorage[i].Dist = geo.CalculateHaversineDistance(from.Lat, from.Lon, to.Lat, to.Lon) * 1000
+	}
+	gs.SetStartShortcutID(int32(len(gs.EdgeStorage)))
+
+	graph := datastructure.NewGraph()
+	if err := graph.InitGraph(gb.nodes, gs, map[string][2]bool{"": {true, true}}, gb.tagStringMap); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// LoadDimacs builds a graph from a 9th DIMACS implementation challenge graph file (.gr) and an optional
+// coordinate file (.co). every arc "a u v w" becomes a directed edge with weight w.
+// coordinates are given in millionths of a degree ("v id lon lat"). if coFile is empty, edge distances equal the arc weights,
+// otherwise they are the haversine distance between the endpoints in meters.
+func LoadDimacs(grFile, coFile string) (*datastructure.Graph, error) {
+	f, err := os.Open(grFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lr := newLineReader(f)
+	var gb *graphBuilder
+	for {
+		line, ok := lr.next("c")
+		if !ok {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "p":
+			if len(fields) != 4 || fields[1] != "sp" {
+				return nil, lr.errorf("expected problem line \"p sp <nodes> <arcs>\"")
+			}
+			n, err := lr.parseInt(fields[2])
+			if err != nil {
+				return nil, err
+			}
+			gb = newGraphBuilder(int(n))
+		case "a":
+			if gb == nil {
+				return nil, lr.errorf("arc before problem line")
+			}
+			if len(fields) != 4 {
+				return nil, lr.errorf("expected arc line \"a <from> <to> <weight>\"")
+			}
+			from, err := lr.parseNodeID(fields[1], len(gb.nodes))
+			if err != nil {
+				return nil, err
+			}
+			to, err := lr.parseNodeID(fields[2], len(gb.nodes))
+			if err != nil {
+				return nil, err
+			}
+			weight, err := lr.parseFloat(fields[3])
+			if err != nil {
+				return nil, err
+			}
+			gb.addEdge(from, to, weight, -1, true)
+		default:
+			return nil, lr.errorf("unknown line type %q", fields[0])
+		}
+	}
+	if err := lr.err(); err != nil {
+		return nil, err
+	}
+	if gb == nil {
+		return nil, lr.errorf("missing problem line")
+	}
+
+	if coFile != "" {
+		if err := readDimacsCoordinates(coFile, gb); err != nil {
+			return nil, err
+		}
+	}
+
+	return gb.build()
+}
+
+func readDimacsCoordinates(coFile string, gb *graphBuilder) error {
+	f, err := os.Open(coFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lr := newLineReader(f)
+	for {
+		line, ok := lr.next("c")
+		if !ok {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "p" {
+			continue
+		}
+		if fields[0] != "v" || len(fields) != 4 {
+			return lr.errorf("expected coordinate line \"v <id> <lon> <lat>\"")
+		}
+		nodeID, err := lr.parseNodeID(fields[1], len(gb.nodes))
+		if err != nil {
+			return err
+		}
+		lon, err := lr.parseFloat(fields[2])
+		if err != nil {
+			return err
+		}
+		lat, err := lr.parseFloat(fields[3])
+		if err != nil {
+			return err
+		}
+		gb.setCoordinate(nodeID, lat/1e6, lon/1e6)
+	}
+	return lr.err()
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+var edgeListColumns = []string{"source", "target", "weight", "source_lat", "source_lon", "target_lat", "target_lon"}
+
+// LoadEdgeList builds a graph from a csv edge list with the columns
+//
+//	source,target,weight,source_lat,source_lon,target_lat,target_lon
+//
+// source and target are arbitrary integer node ids, every row becomes a directed edge.
+// an optional header row is skipped. edge distances are the haversine distance between the endpoints in meters.
+func LoadEdgeList(csvFile string) (*datastructure.Graph, error) {
+	f, err := os.Open(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(edgeListColumns)
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+
+	gb := newGraphBuilder(0)
+	nodeIDMap := make(map[int64]int32)
+	getNode := func(id int64, lat, lon float64) int32 {
+		nodeID, ok := nodeIDMap[id]
+		if !ok {
+			nodeID = gb.addNode()
+			nodeIDMap[id] = nodeID
+			gb.setCoordinate(nodeID, lat, lon)
+		}
+		return nodeID
+	}
+
+	row := 0
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row++
+		if row == 1 && record[0] == edgeListColumns[0] {
+			continue
+		}
+
+		line, _ := r.FieldPos(0)
+		ids := make([]int64, 2)
+		for i := range ids {
+			ids[i], err = strconv.ParseInt(record[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid %s %q", csvFile, line, edgeListColumns[i], record[i])
+			}
+		}
+		values := make([]float64, len(record))
+		for i := 2; i < len(record); i++ {
+			values[i], err = strconv.ParseFloat(record[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid %s %q", csvFile, line, edgeListColumns[i], record[i])
+			}
+		}
+
+		from := getNode(ids[0], values[3], values[4])
+		to := getNode(ids[1], values[5], values[6])
+		gb.addEdge(from, to, values[2], -1, true)
+	}
+
+	return gb.build()
+}
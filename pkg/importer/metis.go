@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// LoadMetis builds a graph from a METIS graph file, e.g. the files written by the kaffpa partitioner.
+// every undirected edge {u, v} becomes one bidirectional edge. edge weights are imported as they are, the kaffpa
+// input encodes them by the edge weight mode it was written with. node weights are skipped, graph nodes have no weight.
+// coordFile is an optional coordinate file with one "lon lat" line per node.
+func LoadMetis(graphFile, coordFile string) (*datastructure.Graph, error) {
+	f, err := os.Open(graphFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lr := newLineReader(f)
+	header, ok := lr.next("%")
+	if !ok {
+		if err := lr.err(); err != nil {
+			return nil, err
+		}
+		return nil, lr.errorf("missing header line")
+	}
+	headerFields := strings.Fields(header)
+	if len(headerFields) < 2 || len(headerFields) > 4 {
+		return nil, lr.errorf("expected header \"<nodes> <edges> [format [ncon]]\"")
+	}
+	n, err := lr.parseInt(headerFields[0])
+	if err != nil {
+		return nil, err
+	}
+	m, err := lr.parseInt(headerFields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	format := "0"
+	if len(headerFields) >= 3 {
+		format = headerFields[2]
+	}
+	hasEdgeWeights := strings.HasSuffix(format, "1")
+	hasNodeWeights := len(format) >= 2 && format[len(format)-2] == '1'
+	ncon := 1
+	if len(headerFields) == 4 {
+		c, err := lr.parseInt(headerFields[3])
+		if err != nil {
+			return nil, err
+		}
+		ncon = int(c)
+	}
+
+	gb := newGraphBuilder(int(n))
+	edgeCount := 0
+	for u := int32(0); u < int32(n); u++ {
+		line, ok := lr.next("%")
+		if !ok {
+			if err := lr.err(); err != nil {
+				return nil, err
+			}
+			return nil, lr.errorf("expected %d node lines, found %d", n, u)
+		}
+		fields := strings.Fields(line)
+		if hasNodeWeights {
+			if len(fields) < ncon {
+				return nil, lr.errorf("missing node weights")
+			}
+			fields = fields[ncon:]
+		}
+
+		step := 1
+		if hasEdgeWeights {
+			step = 2
+		}
+		if len(fields)%step != 0 {
+			return nil, lr.errorf("neighbor without edge weight")
+		}
+
+		for i := 0; i < len(fields); i += step {
+			v, err := lr.parseNodeID(fields[i], int(n))
+			if err != nil {
+				return nil, err
+			}
+			edgeCount++
+			weight := 1.0
+			if hasEdgeWeights {
+				if weight, err = lr.parseFloat(fields[i+1]); err != nil {
+					return nil, err
+				}
+			}
+			if v <= u {
+				// each undirected edge is listed by both endpoints, keep the one from the smaller id.
+				continue
+			}
+			gb.addEdge(u, v, weight, -1, false)
+		}
+	}
+	if int64(edgeCount) != 2*m {
+		return nil, lr.errorf("header declares %d edges but found %d adjacency entries", m, edgeCount)
+	}
+
+	if coordFile != "" {
+		if err := readMetisCoordinates(coordFile, gb); err != nil {
+			return nil, err
+		}
+	}
+
+	return gb.build()
+}
+
+func readMetisCoordinates(coordFile string, gb *graphBuilder) error {
+	f, err := os.Open(coordFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lr := newLineReader(f)
+	for nodeID := int32(0); nodeID < int32(len(gb.nodes)); nodeID++ {
+		line, ok := lr.next("%")
+		if !ok {
+			if err := lr.err(); err != nil {
+				return err
+			}
+			return lr.errorf("expected %d coordinate lines, found %d", len(gb.nodes), nodeID)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return lr.errorf("expected coordinate line \"<lon> <lat>\"")
+		}
+		lon, err := lr.parseFloat(fields[0])
+		if err != nil {
+			return err
+		}
+		lat, err := lr.parseFloat(fields[1])
+		if err != nil {
+			return err
+		}
+		gb.setCoordinate(nodeID, lat, lon)
+	}
+	return nil
+}
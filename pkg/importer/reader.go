@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lineReader reads a text graph file line by line and keeps track of the line number for error messages.
+type lineReader struct {
+	filename string
+	scanner  *bufio.Scanner
+	lineNum  int
+}
+
+func newLineReader(f *os.File) *lineReader {
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 1<<30)
+	return &lineReader{
+		filename: f.Name(),
+		scanner:  scanner,
+	}
+}
+
+// next returns the next line that does not start with commentPrefix. empty lines are returned as is.
+func (lr *lineReader) next(commentPrefix string) (string, bool) {
+	for lr.scanner.Scan() {
+		lr.lineNum++
+		line := strings.TrimSpace(lr.scanner.Text())
+		if !strings.HasPrefix(line, commentPrefix) {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+func (lr *lineReader) err() error {
+	return lr.scanner.Err()
+}
+
+func (lr *lineReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", lr.filename, lr.lineNum, fmt.Sprintf(format, args...))
+}
+
+func (lr *lineReader) parseInt(field string) (int64, error) {
+	v, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return 0, lr.errorf("invalid integer %q", field)
+	}
+	return v, nil
+}
+
+func (lr *lineReader) parseFloat(field string) (float64, error) {
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, lr.errorf("invalid number %q", field)
+	}
+	return v, nil
+}
+
+// parseNodeID converts a 1-indexed node id, as used by the dimacs and metis formats, to an internal node id.
+func (lr *lineReader) parseNodeID(field string, nodeCount int) (int32, error) {
+	id, err := lr.parseInt(field)
+	if err != nil {
+		return 0, err
+	}
+	if id < 1 || id > int64(nodeCount) {
+		return 0, lr.errorf("node id %d out of range [1, %d]", id, nodeCount)
+	}
+	return int32(id - 1), nil
+}
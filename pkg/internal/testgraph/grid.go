@@ -0,0 +1,94 @@
+package testgraph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/geo"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+	"golang.org/x/exp/rand"
+)
+
+const GRID_SPACING = 0.001 // degrees between neighboring grid nodes
+
+// Grid builds a size x size grid of nodes GRID_SPACING degrees apart, node row*size+col is at
+// (row*GRID_SPACING, col*GRID_SPACING). every street between neighbors is dropped, one way, a bidirectional edge
+// or a pair of opposite directed edges with a random speed, so some pairs are unreachable.
+func Grid(t testing.TB, size int, seed uint64) *datastructure.Graph {
+	t.Helper()
+	rng := rand.New(rand.NewSource(seed))
+
+	nodes := make([]datastructure.CHNode, size*size)
+	for id := range nodes {
+		nodes[id] = datastructure.NewCHNode(float64(id/size)*GRID_SPACING, float64(id%size)*GRID_SPACING, int32(id), int32(id))
+	}
+
+	tagStringMap := util.NewIdMap()
+	tagStringMap.GetID("") // empty street name & road class
+	gs := datastructure.NewGraphStorage()
+	addEdge := func(u, v int, directed bool) {
+		edgeID := int32(len(gs.EdgeStorage))
+		dist := geo.CalculateHaversineDistance(nodes[u].Lat, nodes[u].Lon, nodes[v].Lat, nodes[v].Lon) * 1000
+		speed := 200 + rng.Float64()*1300 // meter per minute
+		gs.AppendMapEdgeInfo(datastructure.NewEdgeExtraInfo(0, 0, 1, 0, 0, 0, 0))
+		gs.SetRoundabout(edgeID, false)
+		gs.AppendEdgeStorage(datastructure.NewEdge(edgeID, int32(v), int32(u), -1, dist/speed, dist, directed))
+	}
+	for u := 0; u < size*size; u++ {
+		neighbors := make([]int, 0, 2)
+		if u%size+1 < size {
+			neighbors = append(neighbors, u+1)
+		}
+		if u/size+1 < size {
+			neighbors = append(neighbors, u+size)
+		}
+		for _, v := range neighbors {
+			switch r := rng.Float64(); {
+			case r < 0.05:
+			case r < 0.15:
+				addEdge(u, v, true)
+			case r < 0.25:
+				addEdge(v, u, true)
+			case r < 0.6:
+				addEdge(u, v, false)
+			default:
+				addEdge(u, v, true)
+				addEdge(v, u, true)
+			}
+		}
+	}
+	gs.SetStartShortcutID(int32(len(gs.EdgeStorage)))
+
+	graph := datastructure.NewGraph()
+	if err := graph.InitGraph(nodes, gs, map[string][2]bool{"": {true, true}}, tagStringMap); err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+// CheckPath checks that the edges of path lead from source to target and sum up to cost in metric.
+// bidirectional edges may be traversed from their ToNodeID to their FromNodeID.
+func CheckPath(t testing.TB, graph *datastructure.Graph, metric datastructure.Metric, name string, source, target int32,
+	path []int32, cost float64) {
+	t.Helper()
+	at, pathCost := source, 0.0
+	for _, edgeID := range path {
+		edge := graph.GetOutEdge(edgeID)
+		switch {
+		case edge.FromNodeID == at:
+			at = edge.ToNodeID
+		case !edge.Directed && edge.ToNodeID == at:
+			at = edge.FromNodeID
+		default:
+			t.Fatalf("%s %d -> %d: edge %d (%d -> %d) does not leave %d", name, source, target, edgeID, edge.FromNodeID, edge.ToNodeID, at)
+		}
+		pathCost += metric.Cost(edge)
+	}
+	if at != target {
+		t.Fatalf("%s %d -> %d: path ends at %d", name, source, target, at)
+	}
+	if math.Abs(pathCost-cost) > 1e-6 {
+		t.Fatalf("%s %d -> %d: path cost %f, result cost %f", name, source, target, pathCost, cost)
+	}
+}
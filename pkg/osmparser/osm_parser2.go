@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -35,10 +36,11 @@ type OsmParser struct {
 	tagStringIdMap    util.IDMap
 	nodeIDMap         map[int64]int32
 	maxNodeID         int64
+	copiedNodes       map[int64]int64 // id of a barrier node copy -> osm id of the barrier node
 }
 
 func NewOSMParserV2() *OsmParser {
-	return &OsmParser{
+	p := &OsmParser{
 		wayNodeMap:        make(map[int64]NodeType),
 		relationMemberMap: make(map[int64]struct{}),
 		acceptedNodeMap:   make(map[int64]nodeCoord),
@@ -46,7 +48,21 @@ func NewOSMParserV2() *OsmParser {
 		nodeTag:           make(map[int64]map[int]int),
 		tagStringIdMap:    util.NewIdMap(),
 		nodeIDMap:         make(map[int64]int32),
+		copiedNodes:       make(map[int64]int64),
 	}
+
+	// road classes are stored as uint8 in EdgeExtraInfo, register them first so their ids fit in 8 bits
+	// and are not shifted by the node tags read before the ways.
+	roadClasses := make([]string, 0, len(acceptedHighway))
+	for roadClass := range acceptedHighway {
+		roadClasses = append(roadClasses, roadClass)
+	}
+	sort.Strings(roadClasses)
+	p.tagStringIdMap.GetID("")
+	for _, roadClass := range roadClasses {
+		p.tagStringIdMap.GetID(roadClass)
+	}
+	return p
 }
 func (o *OsmParser) GetTagStringIdMap() util.IDMap {
 	return o.tagStringIdMap
@@ -286,16 +302,24 @@ func (p *OsmParser) Parse(mapFile string) ([]datastructure.CHNode, *datastructur
 
 	processedNodes := make([]datastructure.CHNode, len(p.nodeIDMap))
 
-	nodeId := int32(0)
+	// iterate the nodes by their index instead of over nodeIDMap, so that OrderPos is the same in every run
+	osmNodeIDs := make([]int64, len(p.nodeIDMap))
 	for nodeID, nodeIDX := range p.nodeIDMap {
+		osmNodeIDs[nodeIDX] = nodeID
+	}
+	for nodeIDX, nodeID := range osmNodeIDs {
 		coord := p.acceptedNodeMap[nodeID]
 
 		if val, ok := p.nodeTag[int64(nodeID)][p.tagStringIdMap.GetID(TRAFFIC_LIGHT)]; ok && val == 1 {
 
-			graphStorage.SetTrafficLight(nodeIDX)
+			graphStorage.SetTrafficLight(int32(nodeIDX))
+		}
+		if osmID, ok := p.copiedNodes[nodeID]; ok {
+			graphStorage.SetOsmNodeID(int32(nodeIDX), osmID, true)
+		} else {
+			graphStorage.SetOsmNodeID(int32(nodeIDX), nodeID, false)
 		}
-		processedNodes[nodeIDX] = datastructure.NewCHNode(coord.lat, coord.lon, nodeId, nodeIDX)
-		nodeId++
+		processedNodes[nodeIDX] = datastructure.NewCHNode(coord.lat, coord.lon, int32(nodeIDX), int32(nodeIDX))
 	}
 
 	log.Printf("total edges: %d", len(graphStorage.EdgeStorage))
@@ -306,6 +330,7 @@ func (p *OsmParser) Parse(mapFile string) ([]datastructure.CHNode, *datastructur
 type wayExtraInfo struct {
 	oneWay  bool
 	forward bool
+	wayID   int64
 }
 
 func (p *OsmParser) processWay(way *osm.Way, graphStorage *datastructure.GraphStorage,
@@ -322,7 +347,7 @@ func (p *OsmParser) processWay(way *osm.Way, graphStorage *datastructure.GraphSt
 	maxSpeed := 0.0
 	highwayTypeSpeed := 0.0
 
-	wayExtraInfoData := wayExtraInfo{}
+	wayExtraInfoData := wayExtraInfo{wayID: int64(way.ID)}
 	okvf, okmvf, okvb, okmvb := getReversedOneWay(way)
 	if val := way.Tags.Find("oneway"); val == "yes" || val == "-1" || okvf || okmvf || okvb || okmvb {
 		wayExtraInfoData.oneWay = true
@@ -498,6 +523,7 @@ func (p *OsmParser) copyNode(nodeData node) node {
 		lon: nodeData.coord.lon,
 	}
 	p.maxNodeID++
+	p.copiedNodes[newMaxID] = nodeData.id
 	return node{
 		id: newMaxID,
 		coord: nodeCoord{
@@ -602,6 +628,7 @@ func (p *OsmParser) addEdge(segment []node, tempMap map[string]string, speed flo
 				uint8(lanes),
 				uint8(p.tagStringIdMap.GetID(tempMap[ROAD_CLASS_LINK])),
 				uint32(startPointsIndex), uint32(endPointsIndex),
+				wayExtraInfoData.wayID,
 			),
 			)
 
@@ -633,6 +660,7 @@ func (p *OsmParser) addEdge(segment []node, tempMap map[string]string, speed flo
 				uint8(lanes),
 				uint8(p.tagStringIdMap.GetID(tempMap[ROAD_CLASS_LINK])),
 				uint32(startPointsIndex), uint32(endPointsIndex),
+				wayExtraInfoData.wayID,
 			),
 			)
 
@@ -661,6 +689,7 @@ func (p *OsmParser) addEdge(segment []node, tempMap map[string]string, speed flo
 			uint8(lanes),
 			uint8(p.tagStringIdMap.GetID(tempMap[ROAD_CLASS_LINK])),
 			uint32(startPointsIndex), uint32(endPointsIndex),
+			wayExtraInfoData.wayID,
 		),
 		)
 
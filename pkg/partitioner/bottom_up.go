@@ -0,0 +1,155 @@
+package partitioner
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/exp/rand"
+)
+
+// DriverMode is the order in which the multilevel partitioner builds the levels, every mode writes the same nested cells.
+type DriverMode string
+
+const (
+	DRIVER_MODE_TOP_DOWN  DriverMode = "topdown"  // the whole graph into the largest cells, then every cell into the next smaller cells
+	DRIVER_MODE_BOTTOM_UP DriverMode = "bottomup" // the whole graph into the smallest cells, then adjacent cells merged level by level
+	DRIVER_MODE_HYBRID    DriverMode = "hybrid"   // the top level top down, the levels below bottom up inside every top level cell
+)
+
+func ParseDriverMode(name string) (DriverMode, error) {
+	switch DriverMode(name) {
+	case DRIVER_MODE_TOP_DOWN, DRIVER_MODE_BOTTOM_UP, DRIVER_MODE_HYBRID:
+		return DriverMode(name), nil
+	default:
+		return "", fmt.Errorf("unknown driver mode %q, expected topdown, bottomup or hybrid", name)
+	}
+}
+
+// runBottomUp builds the levels 0 to maxLevel bottom up inside every cell of level maxLevel+1 (or the whole graph):
+// the cell partitioner splits them into cells of at most u[0] nodes, then the quotient graph of the cells of every level
+// is coarsened by merging adjacent cells while they fit into u[level] to get the cells of the next level.
+func (mp *MulitlevelPartitioner) runBottomUp(name string, maxLevel int) error {
+	if maxLevel < 0 {
+		return nil
+	}
+
+	log.Printf("partitioning level 0 with max cell size %d", mp.u[0])
+	for regionId, region := range mp.enclosingCells(0) {
+		if len(region) <= mp.u[0] {
+			mp.overlayNodes[0] = append(mp.overlayNodes[0], region)
+			continue
+		}
+		cp := mp.newCellPartitioner(NewCellGraph(mp.graph, region, mp.edgeWeightMode), mp.seed)
+		partitions, err := cp.PartitionCell(0, regionId, name, mp.u[0])
+		if err != nil {
+			return err
+		}
+		mp.overlayNodes[0] = append(mp.overlayNodes[0], partitions...)
+	}
+	if mp.refinementRounds > 0 {
+		mp.refineLevel(0)
+	}
+	log.Printf("level 0 done, total cells: %d", len(mp.overlayNodes[0]))
+
+	rng := rand.New(rand.NewSource(uint64(mp.seed)))
+	for level := 1; level <= maxLevel; level++ {
+		log.Printf("merging the cells of level %d up to max cell size %d", level-1, mp.u[level])
+		lowerOf := mp.cellAssignment(level - 1)
+		for _, region := range mp.enclosingCells(level) {
+			mp.overlayNodes[level] = append(mp.overlayNodes[level], mp.mergeCells(region, lowerOf, mp.u[level], rng)...)
+		}
+		if mp.refinementRounds > 0 {
+			mp.refineLevel(level)
+		}
+		log.Printf("level %d done, total cells: %d", level, len(mp.overlayNodes[level]))
+	}
+	return nil
+}
+
+// enclosingCells returns the cells of the nearest level above level that was already built, or the whole graph.
+func (mp *MulitlevelPartitioner) enclosingCells(level int) [][]int32 {
+	for l := level + 1; l < mp.l; l++ {
+		if len(mp.overlayNodes[l]) > 0 {
+			return mp.overlayNodes[l]
+		}
+	}
+	return [][]int32{mp.graph.GetNodeIDs()}
+}
+
+// cellAssignment returns the cell of every node in the level, -1 for nodes without a cell.
+func (mp *MulitlevelPartitioner) cellAssignment(level int) []int32 {
+	cellOf := make([]int32, mp.graph.GetNodeCount())
+	for i := range cellOf {
+		cellOf[i] = -1
+	}
+	for cellId, cell := range mp.overlayNodes[level] {
+		for _, nodeId := range cell {
+			cellOf[nodeId] = int32(cellId)
+		}
+	}
+	return cellOf
+}
+
+// mergeCells contracts the cells (given by cellOf) of the region into the quotient graph and merges adjacent cells
+// up to maxCellSize nodes, returns the merged cells.
+func (mp *MulitlevelPartitioner) mergeCells(region []int32, cellOf []int32, maxCellSize int, rng *rand.Rand) [][]int32 {
+	localId := make(map[int32]int32)
+	groupOf := make([]int32, len(region))
+	for i, nodeId := range region {
+		c := cellOf[nodeId]
+		if _, ok := localId[c]; !ok {
+			localId[c] = int32(len(localId))
+		}
+		groupOf[i] = localId[c]
+	}
+	quotient := NewCellGraph(mp.graph, region, mp.edgeWeightMode).Contract(groupOf, len(localId))
+
+	for {
+		matchOf, matchCount := matchAdjacentCells(quotient, int64(maxCellSize), rng)
+		if matchCount == quotient.GetNodeCount() {
+			break
+		}
+		quotient = quotient.Contract(matchOf, matchCount)
+	}
+
+	cells := make([][]int32, quotient.GetNodeCount())
+	for u := range cells {
+		cells[u] = append([]int32(nil), quotient.GetMembers(int32(u))...)
+	}
+	return cells
+}
+
+// matchAdjacentCells pairs every node of the quotient graph with the unmatched neighbor of the highest rating
+// edge weight / (weight u * weight v) whose summed weight fits into maxCellWeight, small cells with strong connections
+// are merged first. returns the group of every node and the number of groups, unmatched nodes are groups of their own.
+func matchAdjacentCells(quotient *CellGraph, maxCellWeight int64, rng *rand.Rand) ([]int32, int) {
+	n := quotient.GetNodeCount()
+	matchOf := make([]int32, n)
+	for i := range matchOf {
+		matchOf[i] = -1
+	}
+
+	groupCount := 0
+	for _, u := range rng.Perm(n) {
+		if matchOf[u] != -1 {
+			continue
+		}
+		wu := int64(quotient.GetNodeWeight(int32(u)))
+		best, bestRating := int32(-1), 0.0
+		quotient.ForEdges(int32(u), func(v int32, weight int32, count int32) {
+			wv := int64(quotient.GetNodeWeight(v))
+			if matchOf[v] != -1 || wu+wv > maxCellWeight {
+				return
+			}
+			if rating := float64(weight) / float64(wu*wv); rating > bestRating {
+				best, bestRating = v, rating
+			}
+		})
+		matchOf[u] = int32(groupCount)
+		if best != -1 {
+			matchOf[best] = int32(groupCount)
+		}
+		groupCount++
+	}
+	return matchOf, groupCount
+}
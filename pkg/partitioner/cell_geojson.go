@@ -0,0 +1,101 @@
+package partitioner
+
+import (
+	"io"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/exporter"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/geo"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+const (
+	cellPolygonGridSize    = 2048  // raster resolution used to compute the cell polygons
+	cellPolygonMaxDistance = 300.0 // meter, cell polygons do not extend farther than this from their nodes
+)
+
+// CellPolygons returns the polygon of every cell of the level, see geo.CellPolygons.
+func CellPolygons(graph *datastructure.Graph, partition *MultilevelPartition, level int) []orb.MultiPolygon {
+	points := make([]datastructure.Coordinate, graph.GetNodeCount())
+	for i, node := range graph.GetNodes() {
+		points[i] = datastructure.NewCoordinate(node.Lat, node.Lon)
+	}
+	return geo.CellPolygons(points, partition.CellAssignment(level), partition.GetCellCount(level),
+		cellPolygonGridSize, cellPolygonMaxDistance)
+}
+
+// WriteCellsGeoJSON writes every cell of the level as a GeoJSON polygon feature.
+func WriteCellsGeoJSON(w io.Writer, graph *datastructure.Graph, partition *MultilevelPartition, level int) error {
+	cellOf := partition.CellAssignment(level)
+	cells := partition.GetCells(level)
+	polygons := CellPolygons(graph, partition, level)
+
+	gw := exporter.NewGeoJSONWriter(w)
+	for cellId, cell := range cells {
+		boundaryVertexCount := 0
+		for _, nodeID := range cell {
+			if isBoundaryVertex(graph, nodeID, cellOf) {
+				boundaryVertexCount++
+			}
+		}
+		parentCellId := int32(-1)
+		if level+1 < partition.GetLevelCount() && len(cell) > 0 {
+			parentCellId = partition.GetCellID(level+1, cell[0])
+		}
+
+		feature := geojson.NewFeature(polygons[cellId])
+		feature.Properties["level"] = level
+		feature.Properties["cell_id"] = cellId
+		feature.Properties["parent_cell_id"] = parentCellId
+		feature.Properties["node_count"] = len(cell)
+		feature.Properties["boundary_vertex_count"] = boundaryVertexCount
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+	return gw.Close()
+}
+
+// WriteCutEdgesGeoJSON writes the edges whose endpoints are in different cells of the level as GeoJSON LineStrings,
+// followed by the boundary vertices of the level as Points.
+func WriteCutEdgesGeoJSON(w io.Writer, graph *datastructure.Graph, partition *MultilevelPartition, level int) error {
+	cellOf := partition.CellAssignment(level)
+
+	gw := exporter.NewGeoJSONWriter(w)
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		edge := graph.GetOutEdge(edgeID)
+		if cellOf[edge.FromNodeID] == cellOf[edge.ToNodeID] {
+			continue
+		}
+		feature := geojson.NewFeature(exporter.EdgeGeometry(graph, edgeID))
+		feature.Properties["type"] = "cut_edge"
+		feature.Properties["level"] = level
+		feature.Properties["edge_id"] = edgeID
+		feature.Properties["from_cell_id"] = cellOf[edge.FromNodeID]
+		feature.Properties["to_cell_id"] = cellOf[edge.ToNodeID]
+		feature.Properties["weight"] = edge.Weight
+		feature.Properties["distance"] = edge.Dist
+		feature.Properties["directed"] = edge.Directed
+		feature.Properties["osm_way_id"] = graph.GraphStorage.GetOsmWayID(edgeID)
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range graph.GetNodes() {
+		if !isBoundaryVertex(graph, node.ID, cellOf) {
+			continue
+		}
+		feature := geojson.NewFeature(orb.Point{node.Lon, node.Lat})
+		feature.Properties["type"] = "boundary_vertex"
+		feature.Properties["level"] = level
+		feature.Properties["node_id"] = node.ID
+		feature.Properties["osm_node_id"] = graph.GraphStorage.GetOsmNodeID(node.ID)
+		feature.Properties["cell_id"] = cellOf[node.ID]
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+	return gw.Close()
+}
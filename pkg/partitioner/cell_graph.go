@@ -0,0 +1,236 @@
+package partitioner
+
+import (
+	"sort"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// CellGraph is the undirected graph of the nodes of a cell that is handed to a CellPartitioner.
+// every CellGraph node stands for one or more road network nodes (its members), the weight of a node is its number of members.
+// two nodes are adjacent if a road edge connects their members, the edge weight is the summed EdgeWeightMode weight
+// of those road edges and edgeCount is the number of road edges.
+type CellGraph struct {
+	graph *datastructure.Graph
+
+	firstEdge  []int32 // edges of node u are firstEdge[u]:firstEdge[u+1]
+	edgeHead   []int32
+	edgeWeight []int32
+	edgeCount  []int32
+
+	nodeWeight  []int32
+	firstMember []int32 // members of node u are members[firstMember[u]:firstMember[u+1]]
+	members     []int32
+}
+
+// NewCellGraph builds the cell graph of nodeIds with one node per road network node, node i stands for nodeIds[i].
+// edges to nodes outside nodeIds and self loops are dropped, edges are weighted by mode.
+func NewCellGraph(graph *datastructure.Graph, nodeIds []int32, mode EdgeWeightMode) *CellGraph {
+	n := len(nodeIds)
+	cg := &CellGraph{
+		graph:       graph,
+		firstEdge:   make([]int32, n+1),
+		edgeHead:    make([]int32, 0, n*2),
+		edgeWeight:  make([]int32, 0, n*2),
+		edgeCount:   make([]int32, 0, n*2),
+		nodeWeight:  make([]int32, n),
+		firstMember: make([]int32, n+1),
+		members:     make([]int32, n),
+	}
+
+	localId := make(map[int32]int32, n)
+	for i, nodeId := range nodeIds {
+		localId[nodeId] = int32(i)
+		cg.nodeWeight[i] = 1
+		cg.firstMember[i+1] = int32(i + 1)
+		cg.members[i] = nodeId
+	}
+
+	type arc struct {
+		head   int32
+		edgeId int32
+		weight int32
+	}
+	arcs := make([]arc, 0)
+	for i, nodeId := range nodeIds {
+		arcs = arcs[:0]
+		handle := func(head int32, edge datastructure.Edge) {
+			if v, ok := localId[head]; ok && v != int32(i) {
+				arcs = append(arcs, arc{head: v, edgeId: edge.EdgeID, weight: mode.Weight(graph, edge)})
+			}
+		}
+		graph.ForOutEdges(nodeId, func(edge datastructure.Edge) { handle(edge.ToNodeID, edge) })
+		graph.ForInEdges(nodeId, func(edge datastructure.Edge) { handle(edge.FromNodeID, edge) })
+
+		// bidirectional edges are seen as out and in edge, every road edge is counted once per neighbor
+		sort.Slice(arcs, func(a, b int) bool {
+			if arcs[a].head != arcs[b].head {
+				return arcs[a].head < arcs[b].head
+			}
+			return arcs[a].edgeId < arcs[b].edgeId
+		})
+		for j, a := range arcs {
+			switch {
+			case j > 0 && arcs[j-1].head == a.head && arcs[j-1].edgeId == a.edgeId:
+			case j > 0 && arcs[j-1].head == a.head:
+				last := len(cg.edgeHead) - 1
+				cg.edgeWeight[last] += a.weight
+				cg.edgeCount[last]++
+			default:
+				cg.edgeHead = append(cg.edgeHead, a.head)
+				cg.edgeWeight = append(cg.edgeWeight, a.weight)
+				cg.edgeCount = append(cg.edgeCount, 1)
+			}
+		}
+		cg.firstEdge[i+1] = int32(len(cg.edgeHead))
+	}
+	return cg
+}
+
+// Contract merges the nodes with the same group, node u goes to group groupOf[u] in [0, groupCount).
+// node weights, edge weights and edge counts of merged nodes and edges are summed, edges inside a group are dropped.
+func (cg *CellGraph) Contract(groupOf []int32, groupCount int) *CellGraph {
+	contracted := &CellGraph{
+		graph:       cg.graph,
+		firstEdge:   make([]int32, groupCount+1),
+		edgeHead:    make([]int32, 0),
+		edgeWeight:  make([]int32, 0),
+		edgeCount:   make([]int32, 0),
+		nodeWeight:  make([]int32, groupCount),
+		firstMember: make([]int32, groupCount+1),
+		members:     make([]int32, len(cg.members)),
+	}
+
+	// nodes of every group, sorted by node id
+	firstNode := make([]int32, groupCount+1)
+	for _, g := range groupOf {
+		firstNode[g+1]++
+	}
+	for g := 0; g < groupCount; g++ {
+		firstNode[g+1] += firstNode[g]
+	}
+	groupNodes := make([]int32, len(groupOf))
+	pos := make([]int32, groupCount)
+	copy(pos, firstNode[:groupCount])
+	for u, g := range groupOf {
+		groupNodes[pos[g]] = int32(u)
+		pos[g]++
+	}
+
+	edgeOf := make([]int32, groupCount) // position of the edge to every group in the current group's edges, -1 if none
+	for g := range edgeOf {
+		edgeOf[g] = -1
+	}
+	memberCount := int32(0)
+	for g := 0; g < groupCount; g++ {
+		start := len(contracted.edgeHead)
+		for _, u := range groupNodes[firstNode[g]:firstNode[g+1]] {
+			contracted.nodeWeight[g] += cg.nodeWeight[u]
+			memberCount += int32(copy(contracted.members[memberCount:], cg.GetMembers(u)))
+
+			for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+				h := groupOf[cg.edgeHead[e]]
+				if int(h) == g {
+					continue
+				}
+				if edgeOf[h] == -1 {
+					edgeOf[h] = int32(len(contracted.edgeHead))
+					contracted.edgeHead = append(contracted.edgeHead, h)
+					contracted.edgeWeight = append(contracted.edgeWeight, 0)
+					contracted.edgeCount = append(contracted.edgeCount, 0)
+				}
+				contracted.edgeWeight[edgeOf[h]] += cg.edgeWeight[e]
+				contracted.edgeCount[edgeOf[h]] += cg.edgeCount[e]
+			}
+		}
+		for _, h := range contracted.edgeHead[start:] {
+			edgeOf[h] = -1
+		}
+		contracted.firstEdge[g+1] = int32(len(contracted.edgeHead))
+		contracted.firstMember[g+1] = memberCount
+	}
+	return contracted
+}
+
+func (cg *CellGraph) GetGraph() *datastructure.Graph {
+	return cg.graph
+}
+
+func (cg *CellGraph) GetNodeCount() int {
+	return len(cg.nodeWeight)
+}
+
+// GetEdgeCount returns the number of undirected edges.
+func (cg *CellGraph) GetEdgeCount() int {
+	return len(cg.edgeHead) / 2
+}
+
+func (cg *CellGraph) GetNodeWeight(u int32) int32 {
+	return cg.nodeWeight[u]
+}
+
+// GetTotalNodeWeight returns the number of road network nodes of the cell.
+func (cg *CellGraph) GetTotalNodeWeight() int {
+	return len(cg.members)
+}
+
+// GetMembers returns the road network nodes node u stands for.
+func (cg *CellGraph) GetMembers(u int32) []int32 {
+	return cg.members[cg.firstMember[u]:cg.firstMember[u+1]]
+}
+
+// ForEdges calls handle for every edge of u with the neighbor, the edge weight and the number of road edges it stands for.
+func (cg *CellGraph) ForEdges(u int32, handle func(v int32, weight int32, count int32)) {
+	for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+		handle(cg.edgeHead[e], cg.edgeWeight[e], cg.edgeCount[e])
+	}
+}
+
+// Expand returns the road network nodes of every part, parts list cell graph nodes.
+func (cg *CellGraph) Expand(parts [][]int32) [][]int32 {
+	cells := make([][]int32, len(parts))
+	for i, part := range parts {
+		cells[i] = make([]int32, 0, len(part))
+		for _, u := range part {
+			cells[i] = append(cells[i], cg.GetMembers(u)...)
+		}
+	}
+	return cells
+}
+
+// Subgraph returns the cell graph induced by nodes, node i of the subgraph is node nodes[i] of cg.
+// edges to nodes outside nodes are dropped.
+func (cg *CellGraph) Subgraph(nodes []int32) *CellGraph {
+	localId := make([]int32, cg.GetNodeCount())
+	for u := range localId {
+		localId[u] = -1
+	}
+	for i, u := range nodes {
+		localId[u] = int32(i)
+	}
+
+	sub := &CellGraph{
+		graph:       cg.graph,
+		firstEdge:   make([]int32, len(nodes)+1),
+		edgeHead:    make([]int32, 0),
+		edgeWeight:  make([]int32, 0),
+		edgeCount:   make([]int32, 0),
+		nodeWeight:  make([]int32, len(nodes)),
+		firstMember: make([]int32, len(nodes)+1),
+		members:     make([]int32, 0),
+	}
+	for i, u := range nodes {
+		sub.nodeWeight[i] = cg.nodeWeight[u]
+		sub.members = append(sub.members, cg.GetMembers(u)...)
+		sub.firstMember[i+1] = int32(len(sub.members))
+		for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+			if v := localId[cg.edgeHead[e]]; v != -1 {
+				sub.edgeHead = append(sub.edgeHead, v)
+				sub.edgeWeight = append(sub.edgeWeight, cg.edgeWeight[e])
+				sub.edgeCount = append(sub.edgeCount, cg.edgeCount[e])
+			}
+		}
+		sub.firstEdge[i+1] = int32(len(sub.edgeHead))
+	}
+	return sub
+}
@@ -0,0 +1,43 @@
+package partitioner
+
+import (
+	"fmt"
+)
+
+// cell partitioners supported by NewCellPartitionerFactory.
+const (
+	CELL_PARTITIONER_KAFFPA     = "kaffpa"
+	CELL_PARTITIONER_FLOWCUTTER = "flowcutter"
+	CELL_PARTITIONER_RCB        = "rcb"
+	CELL_PARTITIONER_HILBERT    = "hilbert"
+	CELL_PARTITIONER_S2         = "s2"
+)
+
+// CellPartitioner splits the nodes of one cell into cells of at most cellSize road network nodes.
+// the returned cells list road network node ids. level, cellId and name identify the cell, e.g. for temporary files.
+type CellPartitioner interface {
+	PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error)
+}
+
+// CellPartitionerFactory creates the partitioner of one cell graph.
+type CellPartitionerFactory func(cell *CellGraph, seed int64) CellPartitioner
+
+// NewCellPartitionerFactory returns the factory of the cell partitioner with the given name.
+func NewCellPartitionerFactory(name string) (CellPartitionerFactory, error) {
+	switch name {
+	case CELL_PARTITIONER_KAFFPA:
+		return func(cell *CellGraph, seed int64) CellPartitioner {
+			return newKaffpaPartitioner(cell, seed)
+		}, nil
+	case CELL_PARTITIONER_FLOWCUTTER:
+		return func(cell *CellGraph, seed int64) CellPartitioner {
+			return newFlowCutterPartitioner(cell, seed)
+		}, nil
+	case CELL_PARTITIONER_RCB, CELL_PARTITIONER_HILBERT, CELL_PARTITIONER_S2:
+		return func(cell *CellGraph, seed int64) CellPartitioner {
+			return newGeometricPartitioner(cell, name)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown cell partitioner %q, expected kaffpa, flowcutter, rcb, hilbert or s2", name)
+	}
+}
@@ -0,0 +1,218 @@
+package partitioner
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/exporter"
+	"github.com/paulmach/orb/geojson"
+)
+
+var ErrPartitionMismatch = errors.New("partitions have a different number of nodes")
+
+// CellDisagreement describes how well a cell of the first partition is matched by the cells of the second partition.
+type CellDisagreement struct {
+	CellID          int32 `json:"cell_id"`
+	NodeCount       int   `json:"node_count"`
+	BestMatchCellID int32 `json:"best_match_cell_id"` // cell of the second partition sharing the most nodes with the cell
+	Overlap         int   `json:"overlap"`            // number of nodes shared with the best match cell
+	Disagreement    int   `json:"disagreement"`       // number of nodes of the cell outside the best match cell
+}
+
+// LevelComparison compares one level of two partitions of the same graph.
+type LevelComparison struct {
+	Level                int                `json:"level"`
+	A                    LevelQuality       `json:"a"`
+	B                    LevelQuality       `json:"b"`
+	AdjustedRandIndex    float64            `json:"adjusted_rand_index"`
+	NormalizedMutualInfo float64            `json:"normalized_mutual_info"`
+	Disagreements        []CellDisagreement `json:"disagreements"` // cells of a with the largest disagreement first
+}
+
+// ComparePartitions compares every level that both partitions have, level i of a with level i of b.
+// the topCells cells with the largest disagreement are reported per level.
+func ComparePartitions(graph *datastructure.Graph, a, b *MultilevelPartition, topCells int) ([]LevelComparison, error) {
+	if a.GetNodeCount() != b.GetNodeCount() || a.GetNodeCount() != graph.GetNodeCount() {
+		return nil, ErrPartitionMismatch
+	}
+
+	comparisons := make([]LevelComparison, min(a.GetLevelCount(), b.GetLevelCount()))
+	for level := range comparisons {
+		cellOfA, cellOfB := a.CellAssignment(level), b.CellAssignment(level)
+		numCellsA, numCellsB := a.GetCellCount(level), b.GetCellCount(level)
+
+		contingency := newContingencyTable(cellOfA, numCellsA, cellOfB, numCellsB)
+		disagreements := contingency.cellDisagreements()
+		sort.SliceStable(disagreements, func(i, j int) bool {
+			return disagreements[i].Disagreement > disagreements[j].Disagreement
+		})
+
+		comparisons[level] = LevelComparison{
+			Level:                level,
+			A:                    ComputeLevelQuality(graph, level, cellOfA, numCellsA),
+			B:                    ComputeLevelQuality(graph, level, cellOfB, numCellsB),
+			AdjustedRandIndex:    contingency.adjustedRandIndex(),
+			NormalizedMutualInfo: contingency.normalizedMutualInfo(),
+			Disagreements:        disagreements[:min(topCells, len(disagreements))],
+		}
+	}
+	return comparisons, nil
+}
+
+// contingencyTable counts the nodes of every pair of cells (cell of a, cell of b) that share at least one node.
+type contingencyTable struct {
+	n      int
+	counts map[[2]int32]int
+	sizesA []int
+	sizesB []int
+}
+
+func newContingencyTable(cellOfA []int32, numCellsA int, cellOfB []int32, numCellsB int) *contingencyTable {
+	t := &contingencyTable{
+		n:      len(cellOfA),
+		counts: make(map[[2]int32]int),
+		sizesA: make([]int, numCellsA),
+		sizesB: make([]int, numCellsB),
+	}
+	for nodeID := range cellOfA {
+		t.counts[[2]int32{cellOfA[nodeID], cellOfB[nodeID]}]++
+		t.sizesA[cellOfA[nodeID]]++
+		t.sizesB[cellOfB[nodeID]]++
+	}
+	return t
+}
+
+func choose2(n int) float64 {
+	return float64(n) * float64(n-1) / 2
+}
+
+// adjustedRandIndex returns the rand index of the two cell assignments adjusted for chance (hubert & arabie).
+// 1 means identical partitions, around 0 means no more agreement than random cell assignments.
+func (t *contingencyTable) adjustedRandIndex() float64 {
+	if t.n < 2 {
+		return 1
+	}
+	index := 0.0
+	for _, count := range t.counts {
+		index += choose2(count)
+	}
+	sumA, sumB := 0.0, 0.0
+	for _, size := range t.sizesA {
+		sumA += choose2(size)
+	}
+	for _, size := range t.sizesB {
+		sumB += choose2(size)
+	}
+
+	expected := sumA * sumB / choose2(t.n)
+	maxIndex := (sumA + sumB) / 2
+	if maxIndex == expected {
+		return 1
+	}
+	return (index - expected) / (maxIndex - expected)
+}
+
+// normalizedMutualInfo returns the mutual information of the two cell assignments divided by the mean of their entropies.
+func (t *contingencyTable) normalizedMutualInfo() float64 {
+	n := float64(t.n)
+	entropy := func(sizes []int) float64 {
+		h := 0.0
+		for _, size := range sizes {
+			if size > 0 {
+				p := float64(size) / n
+				h -= p * math.Log(p)
+			}
+		}
+		return h
+	}
+
+	mutualInfo := 0.0
+	for cells, count := range t.counts {
+		pab := float64(count) / n
+		mutualInfo += pab * math.Log(pab*n*n/(float64(t.sizesA[cells[0]])*float64(t.sizesB[cells[1]])))
+	}
+
+	hA, hB := entropy(t.sizesA), entropy(t.sizesB)
+	if hA+hB == 0 {
+		return 1
+	}
+	return 2 * mutualInfo / (hA + hB)
+}
+
+// cellDisagreements returns the disagreement of every cell of a, ordered by cell id.
+func (t *contingencyTable) cellDisagreements() []CellDisagreement {
+	disagreements := make([]CellDisagreement, len(t.sizesA))
+	for cellId := range disagreements {
+		disagreements[cellId] = CellDisagreement{
+			CellID:          int32(cellId),
+			NodeCount:       t.sizesA[cellId],
+			BestMatchCellID: -1,
+		}
+	}
+	for cells, count := range t.counts {
+		d := &disagreements[cells[0]]
+		if count > d.Overlap || (count == d.Overlap && cells[1] < d.BestMatchCellID) {
+			d.Overlap = count
+			d.BestMatchCellID = cells[1]
+		}
+	}
+	for cellId := range disagreements {
+		disagreements[cellId].Disagreement = disagreements[cellId].NodeCount - disagreements[cellId].Overlap
+	}
+	return disagreements
+}
+
+// WriteComparisonGeoJSON writes the difference between level of partitions a and b as GeoJSON:
+// the cell polygons of a with their best match cell of b and disagreement,
+// followed by the edges that are cut in only one of the partitions as LineStrings.
+func WriteComparisonGeoJSON(w io.Writer, graph *datastructure.Graph, a, b *MultilevelPartition, level int) error {
+	if a.GetNodeCount() != b.GetNodeCount() || a.GetNodeCount() != graph.GetNodeCount() {
+		return ErrPartitionMismatch
+	}
+	cellOfA, cellOfB := a.CellAssignment(level), b.CellAssignment(level)
+	contingency := newContingencyTable(cellOfA, a.GetCellCount(level), cellOfB, b.GetCellCount(level))
+	polygons := CellPolygons(graph, a, level)
+
+	gw := exporter.NewGeoJSONWriter(w)
+	for _, d := range contingency.cellDisagreements() {
+		feature := geojson.NewFeature(polygons[d.CellID])
+		feature.Properties["type"] = "cell"
+		feature.Properties["level"] = level
+		feature.Properties["cell_id"] = d.CellID
+		feature.Properties["node_count"] = d.NodeCount
+		feature.Properties["best_match_cell_id"] = d.BestMatchCellID
+		feature.Properties["disagreement"] = d.Disagreement
+		feature.Properties["disagreement_ratio"] = 0.0
+		if d.NodeCount > 0 {
+			feature.Properties["disagreement_ratio"] = float64(d.Disagreement) / float64(d.NodeCount)
+		}
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		edge := graph.GetOutEdge(edgeID)
+		cutInA := cellOfA[edge.FromNodeID] != cellOfA[edge.ToNodeID]
+		cutInB := cellOfB[edge.FromNodeID] != cellOfB[edge.ToNodeID]
+		if cutInA == cutInB {
+			continue
+		}
+		feature := geojson.NewFeature(exporter.EdgeGeometry(graph, edgeID))
+		feature.Properties["type"] = "cut_edge"
+		feature.Properties["level"] = level
+		feature.Properties["edge_id"] = edgeID
+		if cutInA {
+			feature.Properties["cut_in"] = "a"
+		} else {
+			feature.Properties["cut_in"] = "b"
+		}
+		if err := gw.Write(feature); err != nil {
+			return err
+		}
+	}
+	return gw.Close()
+}
@@ -0,0 +1,68 @@
+package partitioner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// EdgeWeightMode is the weight of the road edges in the graph handed to the cell partitioners,
+// the partitioners minimize the summed weight of the cut edges.
+type EdgeWeightMode string
+
+const (
+	EDGE_WEIGHT_UNIFORM    EdgeWeightMode = "uniform"   // 1 per road edge, the number of cut edges
+	EDGE_WEIGHT_TIME       EdgeWeightMode = "time"      // Edge.Weight in 1/100 minutes + 1
+	EDGE_WEIGHT_DISTANCE   EdgeWeightMode = "distance"  // Edge.Dist in meters + 1
+	EDGE_WEIGHT_ROAD_CLASS EdgeWeightMode = "roadclass" // capacity of the road class, major roads are expensive to cut
+)
+
+func ParseEdgeWeightMode(name string) (EdgeWeightMode, error) {
+	switch EdgeWeightMode(name) {
+	case EDGE_WEIGHT_UNIFORM, EDGE_WEIGHT_TIME, EDGE_WEIGHT_DISTANCE, EDGE_WEIGHT_ROAD_CLASS:
+		return EdgeWeightMode(name), nil
+	default:
+		return "", fmt.Errorf("unknown edge weight mode %q, expected uniform, time, distance or roadclass", name)
+	}
+}
+
+// roadClassCapacity is the capacity of every road class, links have the capacity of their road class.
+// road classes not listed and edges without edge info have capacity 1.
+var roadClassCapacity = map[string]int32{
+	"motorway":      10,
+	"motorroad":     8,
+	"trunk":         8,
+	"primary":       6,
+	"secondary":     4,
+	"tertiary":      3,
+	"unclassified":  2,
+	"residential":   2,
+	"road":          2,
+	"living_street": 1,
+	"service":       1,
+	"track":         1,
+	"private":       1,
+}
+
+// Weight returns the partitioning weight of the road edge.
+func (m EdgeWeightMode) Weight(graph *datastructure.Graph, edge datastructure.Edge) int32 {
+	switch m {
+	case EDGE_WEIGHT_UNIFORM:
+		return 1
+	case EDGE_WEIGHT_DISTANCE:
+		return int32(edge.Dist) + 1
+	case EDGE_WEIGHT_ROAD_CLASS:
+		info, _ := graph.GraphStorage.GetEdgeExtraInfo(edge.EdgeID, false)
+		roadClass := graph.TagStringIDMap.GetStr(int(info.RoadClass))
+		if roadClass == "" {
+			roadClass = graph.TagStringIDMap.GetStr(int(info.RoadClassLink))
+		}
+		if capacity, ok := roadClassCapacity[strings.TrimSuffix(roadClass, "_link")]; ok {
+			return capacity
+		}
+		return 1
+	default:
+		return int32(edge.Weight*100) + 1
+	}
+}
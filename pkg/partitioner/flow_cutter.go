@@ -0,0 +1,336 @@
+package partitioner
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/flow"
+	"golang.org/x/exp/rand"
+)
+
+const (
+	flowCutterPairs = 4 // random source/sink pairs per bisection, their pareto fronts are merged
+)
+
+// ParetoCut is a cut of the pareto front of a FlowCutter bisection: no other cut found has a smaller cut size
+// with a larger smaller side.
+type ParetoCut struct {
+	CutSize         int64 // summed edge weight of the cut edges
+	SmallSideWeight int64 // road network nodes of the smaller side
+
+	side []int32 // cell graph nodes of one side
+}
+
+// Imbalance returns how much larger the larger side is than half of totalWeight, 0 for a perfect bisection.
+func (pc ParetoCut) Imbalance(totalWeight int64) float64 {
+	return float64(totalWeight-pc.SmallSideWeight)/(float64(totalWeight)/2) - 1
+}
+
+// FlowCutterPartitioner recursively bisects a cell with FlowCutter (hamann & strasser): starting from a source and
+// a sink node it computes a maximum flow, then repeatedly grows the lighter of the source side and the sink side of
+// the minimum cut by one pierced node and augments the flow. every minimum cut seen on the way is a candidate,
+// the cuts that are not dominated in (cut size, balance) form the pareto front.
+// every bisection picks the smallest cut of the front whose sides can still be split into cells of at most cellSize.
+type FlowCutterPartitioner struct {
+	cell *CellGraph
+	seed int64
+
+	network *flow.Network // reused by every bisection
+}
+
+func newFlowCutterPartitioner(cell *CellGraph, seed int64) *FlowCutterPartitioner {
+	return &FlowCutterPartitioner{
+		cell:    cell,
+		seed:    seed,
+		network: flow.NewNetwork(0),
+	}
+}
+
+func (fc *FlowCutterPartitioner) PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
+	rng := rand.New(rand.NewSource(uint64(fc.seed)))
+	cells := make([][]int32, 0)
+	fc.bisect(fc.cell, int64(cellSize), rng, func(front []ParetoCut, chosen int) {
+		total := int64(fc.cell.GetTotalNodeWeight())
+		log.Printf("flowcutter level %d cell %d: %d nodes, pareto front of %d cuts", level, cellId, total, len(front))
+		for i, cut := range front {
+			mark := ""
+			if i == chosen {
+				mark = " (chosen)"
+			}
+			log.Printf("  cut size %d, smaller side %d, imbalance %.3f%s", cut.CutSize, cut.SmallSideWeight, cut.Imbalance(total), mark)
+		}
+	}, &cells)
+	return cells, nil
+}
+
+// bisect splits cg into two sides with the best cut of the pareto front and recurses until the cells have
+// at most cellSize node weight. report is called for the first bisection only.
+func (fc *FlowCutterPartitioner) bisect(cg *CellGraph, cellSize int64, rng *rand.Rand, report func(front []ParetoCut, chosen int), cells *[][]int32) {
+	total := int64(cg.GetTotalNodeWeight())
+	if total <= cellSize || cg.GetNodeCount() < 2 {
+		*cells = append(*cells, append([]int32(nil), cg.members...))
+		return
+	}
+
+	front := fc.paretoFront(cg, rng)
+
+	// the larger side must fit into the larger half of the ceil(total / cellSize) cells
+	k := int64(math.Ceil(float64(total) / float64(cellSize)))
+	largeLimit := (k + 1) / 2 * cellSize
+	chosen := len(front) - 1
+	for i, cut := range front {
+		if total-cut.SmallSideWeight <= largeLimit {
+			chosen = i
+			break
+		}
+	}
+	if report != nil {
+		report(front, chosen)
+	}
+
+	inSide := make([]bool, cg.GetNodeCount())
+	for _, u := range front[chosen].side {
+		inSide[u] = true
+	}
+	rest := make([]int32, 0, cg.GetNodeCount()-len(front[chosen].side))
+	for u := int32(0); u < int32(cg.GetNodeCount()); u++ {
+		if !inSide[u] {
+			rest = append(rest, u)
+		}
+	}
+	fc.bisect(cg.Subgraph(front[chosen].side), cellSize, rng, nil, cells)
+	fc.bisect(cg.Subgraph(rest), cellSize, rng, nil, cells)
+}
+
+// paretoFront runs FlowCutter from flowCutterPairs random source/sink pairs and returns the merged pareto front,
+// sorted by increasing cut size and increasing balance.
+func (fc *FlowCutterPartitioner) paretoFront(cg *CellGraph, rng *rand.Rand) []ParetoCut {
+	n := cg.GetNodeCount()
+	network := fc.network
+	network.Reset(n)
+	for u := int32(0); u < int32(n); u++ {
+		cg.ForEdges(u, func(v int32, weight int32, count int32) {
+			if u < v {
+				network.AddEdge(u, v, int64(weight), int64(weight))
+			}
+		})
+	}
+
+	cuts := make([]ParetoCut, 0)
+	for pair := 0; pair < flowCutterPairs; pair++ {
+		s := int32(rng.Intn(n))
+		t := fc.farthestNode(cg, s)
+		if t == s {
+			t = (s + 1) % int32(n)
+		}
+		cuts = append(cuts, fc.cut(cg, s, t)...)
+	}
+
+	sort.Slice(cuts, func(i, j int) bool {
+		if cuts[i].CutSize != cuts[j].CutSize {
+			return cuts[i].CutSize < cuts[j].CutSize
+		}
+		return cuts[i].SmallSideWeight > cuts[j].SmallSideWeight
+	})
+	front := make([]ParetoCut, 0)
+	for _, cut := range cuts {
+		if len(front) == 0 || cut.SmallSideWeight > front[len(front)-1].SmallSideWeight {
+			front = append(front, cut)
+		}
+	}
+	return front
+}
+
+// farthestNode returns the last node reached by a bfs from s.
+func (fc *FlowCutterPartitioner) farthestNode(cg *CellGraph, s int32) int32 {
+	visited := make([]bool, cg.GetNodeCount())
+	visited[s] = true
+	queue := []int32{s}
+	for head := 0; head < len(queue); head++ {
+		cg.ForEdges(queue[head], func(v int32, weight int32, count int32) {
+			if !visited[v] {
+				visited[v] = true
+				queue = append(queue, v)
+			}
+		})
+	}
+	return queue[len(queue)-1]
+}
+
+// flowCutterSide is the source or the sink side of FlowCutter. reached lists the nodes that reach a terminal
+// of the side in the residual network (for the sink side) or are reached from one (for the source side),
+// the terminals are reached[:terminalCount].
+type flowCutterSide struct {
+	isSource      bool
+	reachable     []bool
+	terminal      []bool
+	reached       []int32
+	terminalCount int
+	weight        int64
+
+	frontier []int32 // nodes adjacent to reached, candidates for piercing
+	deferred []int32 // frontier nodes reachable by the other side, piercing them increases the flow
+}
+
+func newFlowCutterSide(n int, isSource bool) *flowCutterSide {
+	return &flowCutterSide{
+		isSource:  isSource,
+		reachable: make([]bool, n),
+		terminal:  make([]bool, n),
+		reached:   make([]int32, 0),
+		frontier:  make([]int32, 0),
+		deferred:  make([]int32, 0),
+	}
+}
+
+// cut runs FlowCutter from source s and sink t and returns a minimum cut of every flow value it passes,
+// the most balanced one seen with that flow value.
+func (fc *FlowCutterPartitioner) cut(cg *CellGraph, s, t int32) []ParetoCut {
+	n := cg.GetNodeCount()
+	total := int64(cg.GetTotalNodeWeight())
+	source := newFlowCutterSide(n, true)
+	sink := newFlowCutterSide(n, false)
+	fc.addTerminal(cg, source, s)
+	fc.addTerminal(cg, sink, t)
+
+	flowValue := fc.network.Dinic([]int32{s}, []int32{t})
+	fc.reach(cg, source, 0)
+	fc.reach(cg, sink, 0)
+
+	// the best cut of the current flow value, the side is the prefix of side.reached
+	type candidate struct {
+		side      *flowCutterSide
+		length    int
+		smallSide int64
+	}
+	best := candidate{}
+	cuts := make([]ParetoCut, 0)
+	commit := func() {
+		if best.side != nil {
+			cuts = append(cuts, ParetoCut{
+				CutSize:         flowValue,
+				SmallSideWeight: best.smallSide,
+				side:            append([]int32(nil), best.side.reached[:best.length]...),
+			})
+		}
+		best = candidate{}
+	}
+
+	nextFree := int32(0)
+	for {
+		for _, side := range []*flowCutterSide{source, sink} {
+			if smallSide := min(side.weight, total-side.weight); smallSide > best.smallSide {
+				best = candidate{side: side, length: len(side.reached), smallSide: smallSide}
+			}
+		}
+		if 2*best.smallSide >= total-1 {
+			break
+		}
+
+		grow, other := source, sink
+		if sink.weight < source.weight {
+			grow, other = sink, source
+		}
+		x := fc.pierceNode(grow, other)
+		for x == -1 && nextFree < int32(n) {
+			// the lighter side has no neighbors left, continue with any node of another component
+			if !grow.reachable[nextFree] && !other.reachable[nextFree] {
+				x = nextFree
+			}
+			nextFree++
+		}
+		if x == -1 {
+			break
+		}
+
+		// the whole side becomes terminals, then x is pierced
+		grow.terminalCount = len(grow.reached)
+		for _, u := range grow.reached {
+			grow.terminal[u] = true
+		}
+		augments := other.reachable[x]
+		start := len(grow.reached)
+		fc.addTerminal(cg, grow, x)
+		if !augments {
+			// the flow and the other side stay the same, the side grows by the nodes x reaches
+			fc.reach(cg, grow, start)
+			continue
+		}
+
+		commit()
+		flowValue += fc.network.ContinueDinic(source.reached[:source.terminalCount], sink.reached[:sink.terminalCount])
+		for _, side := range []*flowCutterSide{source, sink} {
+			for _, u := range side.reached[side.terminalCount:] {
+				side.reachable[u] = false
+				side.weight -= int64(cg.GetNodeWeight(u))
+			}
+			side.reached = side.reached[:side.terminalCount]
+			// the cut moved, candidates collected before the augmentation may no longer be adjacent to the side
+			side.frontier = side.frontier[:0]
+			side.deferred = side.deferred[:0]
+			fc.reach(cg, side, 0)
+		}
+	}
+	commit()
+	return cuts
+}
+
+// addTerminal adds u as terminal of the side.
+func (fc *FlowCutterPartitioner) addTerminal(cg *CellGraph, side *flowCutterSide, u int32) {
+	side.terminal[u] = true
+	side.reachable[u] = true
+	side.reached = append(side.reached, u)
+	side.terminalCount = len(side.reached)
+	side.weight += int64(cg.GetNodeWeight(u))
+}
+
+// reach extends the side by a residual bfs from side.reached[start:] and collects the piercing candidates.
+func (fc *FlowCutterPartitioner) reach(cg *CellGraph, side *flowCutterSide, start int) {
+	network := fc.network
+	for i := start; i < len(side.reached); i++ {
+		u := side.reached[i]
+		for _, arc := range network.GetArcs(u) {
+			v := network.GetHead(arc)
+			if side.reachable[v] {
+				continue
+			}
+			// the source side follows residual arcs u->v, the sink side residual arcs v->u
+			residual := network.GetResidual(arc)
+			if !side.isSource {
+				residual = network.GetResidual(arc ^ 1)
+			}
+			if residual > 0 {
+				side.reachable[v] = true
+				side.reached = append(side.reached, v)
+				side.weight += int64(cg.GetNodeWeight(v))
+			} else {
+				side.frontier = append(side.frontier, v)
+			}
+		}
+	}
+}
+
+// pierceNode returns the next node to add to the terminals of grow, preferring nodes that other cannot reach
+// so that the flow does not increase. returns -1 if grow has no neighbor left.
+func (fc *FlowCutterPartitioner) pierceNode(grow, other *flowCutterSide) int32 {
+	for len(grow.frontier) > 0 {
+		v := grow.frontier[len(grow.frontier)-1]
+		grow.frontier = grow.frontier[:len(grow.frontier)-1]
+		switch {
+		case grow.reachable[v] || other.terminal[v]:
+		case other.reachable[v]:
+			grow.deferred = append(grow.deferred, v)
+		default:
+			return v
+		}
+	}
+	for len(grow.deferred) > 0 {
+		v := grow.deferred[len(grow.deferred)-1]
+		grow.deferred = grow.deferred[:len(grow.deferred)-1]
+		if !grow.reachable[v] && !other.terminal[v] {
+			return v
+		}
+	}
+	return -1
+}
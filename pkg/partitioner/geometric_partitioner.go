@@ -0,0 +1,200 @@
+package partitioner
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+const (
+	geometricHilbertOrder = 16 // the hilbert curve fills a 2^16 x 2^16 grid over the bounding box of the cell
+)
+
+// GeometricPartitioner splits a cell by the coordinates of its nodes only, ignoring the edges:
+//   - rcb: recursive coordinate bisection along the longer axis of the bounding box, the split point divides
+//     the node weight in proportion to the number of cells of both halves.
+//   - hilbert: nodes sorted along a hilbert curve over the bounding box, cut into consecutive chunks of at most cellSize.
+//   - s2: nodes grouped by s2 cell, cells heavier than cellSize are split into their children
+//     and consecutive sibling groups are merged while they fit into cellSize.
+//
+// a contracted cell graph node is placed at the mean coordinate of its members.
+type GeometricPartitioner struct {
+	cell   *CellGraph
+	method string
+	lat    []float64
+	lon    []float64
+}
+
+func newGeometricPartitioner(cell *CellGraph, method string) *GeometricPartitioner {
+	graph := cell.GetGraph()
+	n := cell.GetNodeCount()
+	gp := &GeometricPartitioner{
+		cell:   cell,
+		method: method,
+		lat:    make([]float64, n),
+		lon:    make([]float64, n),
+	}
+	for u := int32(0); u < int32(n); u++ {
+		members := cell.GetMembers(u)
+		for _, nodeId := range members {
+			node := graph.GetNode(nodeId)
+			gp.lat[u] += node.Lat
+			gp.lon[u] += node.Lon
+		}
+		gp.lat[u] /= float64(len(members))
+		gp.lon[u] /= float64(len(members))
+	}
+	return gp
+}
+
+func (gp *GeometricPartitioner) PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
+	nodes := make([]int32, gp.cell.GetNodeCount())
+	for i := range nodes {
+		nodes[i] = int32(i)
+	}
+
+	var parts [][]int32
+	switch gp.method {
+	case CELL_PARTITIONER_RCB:
+		parts = gp.bisect(nodes, int64(cellSize), make([][]int32, 0))
+	case CELL_PARTITIONER_HILBERT:
+		parts = gp.hilbertChunks(nodes, int64(cellSize))
+	default:
+		parts = gp.s2Groups(nodes, int64(cellSize))
+	}
+	return gp.cell.Expand(parts), nil
+}
+
+func (gp *GeometricPartitioner) weight(nodes []int32) int64 {
+	weight := int64(0)
+	for _, u := range nodes {
+		weight += int64(gp.cell.GetNodeWeight(u))
+	}
+	return weight
+}
+
+// boundingBox returns the lat/lon bounding box of nodes.
+func (gp *GeometricPartitioner) boundingBox(nodes []int32) (minLat, minLon, maxLat, maxLon float64) {
+	minLat, minLon = math.Inf(1), math.Inf(1)
+	maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+	for _, u := range nodes {
+		minLat, maxLat = math.Min(minLat, gp.lat[u]), math.Max(maxLat, gp.lat[u])
+		minLon, maxLon = math.Min(minLon, gp.lon[u]), math.Max(maxLon, gp.lon[u])
+	}
+	return minLat, minLon, maxLat, maxLon
+}
+
+// bisect splits nodes along the longer axis of their bounding box until every part has at most cellSize weight,
+// the parts are appended to parts.
+func (gp *GeometricPartitioner) bisect(nodes []int32, cellSize int64, parts [][]int32) [][]int32 {
+	weight := gp.weight(nodes)
+	if weight <= cellSize || len(nodes) < 2 {
+		return append(parts, nodes)
+	}
+
+	// a degree of longitude is cos(lat) times shorter than a degree of latitude
+	minLat, minLon, maxLat, maxLon := gp.boundingBox(nodes)
+	lonScale := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	coord := gp.lat
+	if (maxLon-minLon)*lonScale > maxLat-minLat {
+		coord = gp.lon
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return coord[nodes[i]] < coord[nodes[j]]
+	})
+
+	// the first half gets k/2 of the k cells
+	k := int64(math.Ceil(float64(weight) / float64(cellSize)))
+	target := weight * (k / 2) / k
+	split, prefix := 0, int64(0)
+	for split < len(nodes)-1 && prefix < target {
+		prefix += int64(gp.cell.GetNodeWeight(nodes[split]))
+		split++
+	}
+	split = max(split, 1)
+
+	parts = gp.bisect(nodes[:split], cellSize, parts)
+	return gp.bisect(nodes[split:], cellSize, parts)
+}
+
+// hilbertChunks sorts nodes along a hilbert curve and cuts them into chunks of at most cellSize weight.
+func (gp *GeometricPartitioner) hilbertChunks(nodes []int32, cellSize int64) [][]int32 {
+	minLat, minLon, maxLat, maxLon := gp.boundingBox(nodes)
+	gridSize := float64(uint32(1)<<geometricHilbertOrder - 1)
+	toGrid := func(v, lo, hi float64) uint32 {
+		if hi <= lo {
+			return 0
+		}
+		return uint32((v - lo) / (hi - lo) * gridSize)
+	}
+
+	keys := make([]uint64, gp.cell.GetNodeCount())
+	for _, u := range nodes {
+		keys[u] = util.HilbertIndex(toGrid(gp.lon[u], minLon, maxLon), toGrid(gp.lat[u], minLat, maxLat), geometricHilbertOrder)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return keys[nodes[i]] < keys[nodes[j]]
+	})
+
+	parts := make([][]int32, 0)
+	start, weight := 0, int64(0)
+	for i, u := range nodes {
+		w := int64(gp.cell.GetNodeWeight(u))
+		if i > start && weight+w > cellSize {
+			parts = append(parts, nodes[start:i])
+			start, weight = i, 0
+		}
+		weight += w
+	}
+	return append(parts, nodes[start:])
+}
+
+// s2Groups groups nodes by s2 cell, starting with the six face cells.
+func (gp *GeometricPartitioner) s2Groups(nodes []int32, cellSize int64) [][]int32 {
+	leaf := make([]s2.CellID, gp.cell.GetNodeCount())
+	for _, u := range nodes {
+		leaf[u] = s2.CellIDFromLatLng(s2.LatLngFromDegrees(gp.lat[u], gp.lon[u]))
+	}
+	// nodes of the same s2 cell are consecutive when sorted by leaf cell id
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return leaf[nodes[i]] < leaf[nodes[j]]
+	})
+	return gp.s2Split(nodes, leaf, -1, cellSize)
+}
+
+// s2Split groups nodes, which lie in one s2 cell of the given level (-1 for the whole sphere),
+// by the cells of the next level.
+func (gp *GeometricPartitioner) s2Split(nodes []int32, leaf []s2.CellID, level int, cellSize int64) [][]int32 {
+	if gp.weight(nodes) <= cellSize || level == s2.MaxLevel {
+		return [][]int32{nodes}
+	}
+
+	groups := make([][]int32, 0)
+	for start := 0; start < len(nodes); {
+		child := leaf[nodes[start]].Parent(level + 1)
+		end := start + 1
+		for end < len(nodes) && leaf[nodes[end]].Parent(level+1) == child {
+			end++
+		}
+		groups = append(groups, gp.s2Split(nodes[start:end], leaf, level+1, cellSize)...)
+		start = end
+	}
+
+	// merge consecutive groups, they are sorted along the s2 hilbert curve
+	merged := make([][]int32, 0, len(groups))
+	weight := int64(0)
+	for _, group := range groups {
+		w := gp.weight(group)
+		if len(merged) > 0 && weight+w <= cellSize {
+			last := len(merged) - 1
+			merged[last] = append(merged[last], group...)
+			weight += w
+			continue
+		}
+		merged = append(merged, append([]int32(nil), group...))
+		weight = w
+	}
+	return merged
+}
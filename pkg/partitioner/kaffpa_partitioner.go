@@ -11,24 +11,21 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
-
-	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
 )
 
 type KaffpaPartitioner struct {
-	nodeIds                        []int32
-	kaffpaNodeIdsToOriginalNodeIds []int32
-	graph                          *datastructure.Graph
+	cell *CellGraph
+	seed int64
 }
 
-func newKaffpaPartitioner(graph *datastructure.Graph, parentCellNodeIds []int32) *KaffpaPartitioner {
+func newKaffpaPartitioner(cell *CellGraph, seed int64) *KaffpaPartitioner {
 	return &KaffpaPartitioner{
-		graph:   graph,
-		nodeIds: parentCellNodeIds,
+		cell: cell,
+		seed: seed,
 	}
 }
 
-func (kp *KaffpaPartitioner) partitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
+func (kp *KaffpaPartitioner) PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
 
 	err := kp.saveGraphToFile(fmt.Sprintf("./data/%s_level_%d_cell_%d", name, level, cellId))
 	if err != nil {
@@ -42,10 +39,10 @@ func (kp *KaffpaPartitioner) partitionCell(level, cellId int, name string, cellS
 }
 
 func (kp *KaffpaPartitioner) runKaffpa(inputName, outputName string, cellSize int) error {
-	k := int(math.Ceil(float64(len(kp.nodeIds)) / float64(cellSize)))
+	k := int(math.Ceil(float64(kp.cell.GetTotalNodeWeight()) / float64(cellSize)))
 	log.Printf("running kaffpa with k=%d, cellSize=%d", k, cellSize)
 	os, err := exec.Command("/home/lintangbs/KaHIP/deploy/kaffpa", inputName, fmt.Sprintf("--output=%s", outputName),
-		fmt.Sprintf("--k=%v", k), fmt.Sprintf("--preconfiguration=strong")).CombinedOutput()
+		fmt.Sprintf("--k=%v", k), fmt.Sprintf("--preconfiguration=strong"), fmt.Sprintf("--seed=%d", kp.seed)).CombinedOutput()
 	if err != nil || len(os) == 0 {
 		return err
 	}
@@ -77,7 +74,7 @@ func (kp *KaffpaPartitioner) readPartitionResult(filename string) ([][]int32, er
 
 	partitionResult := make([][]int32, 0)
 
-	for i := 0; i < len(kp.nodeIds); i++ {
+	for i := 0; i < kp.cell.GetNodeCount(); i++ {
 		line, err := readLine()
 		if err != nil {
 			return [][]int32{}, err
@@ -93,12 +90,15 @@ func (kp *KaffpaPartitioner) readPartitionResult(filename string) ([][]int32, er
 			partitionResult = append(partitionResult, []int32{})
 		}
 
-		partitionResult[partId] = append(partitionResult[partId], kp.kaffpaNodeIdsToOriginalNodeIds[i])
+		partitionResult[partId] = append(partitionResult[partId], int32(i))
 	}
 
-	return partitionResult, nil
+	// kaffpa partitions the cell graph nodes, expand them to the road network nodes they stand for
+	return kp.cell.Expand(partitionResult), nil
 }
 
+// saveGraphToFile writes the cell graph in the metis format, node ids are 1-based.
+// node weights (format 11) are only written for contracted cell graphs, so that kaffpa balances road network nodes.
 func (kp *KaffpaPartitioner) saveGraphToFile(filename string) error {
 
 	file, err := os.Create(fmt.Sprintf(`%v.graph`, filename))
@@ -106,78 +106,34 @@ func (kp *KaffpaPartitioner) saveGraphToFile(filename string) error {
 		return err
 	}
 	defer file.Close()
-	nodeEdges := make([][]edge, len(kp.nodeIds))
-	adjs := make([]map[int32]bool, len(kp.nodeIds))
-	inCell := make(map[int32]bool, len(kp.nodeIds))
-
-	kp.kaffpaNodeIdsToOriginalNodeIds = make([]int32, len(kp.nodeIds))
-	originalNodeIdToKaffpaNodeId := make(map[int32]int32, len(kp.nodeIds))
-	for idx, id := range kp.nodeIds {
-		inCell[id] = true
-		originalNodeIdToKaffpaNodeId[id] = int32(idx + 1)
-		kp.kaffpaNodeIdsToOriginalNodeIds[idx] = id
-	}
-
-	for id, nodeId := range kp.nodeIds {
-		adjs[id] = make(map[int32]bool)
-
-		for _, outEdgeIDx := range kp.graph.GetNodeFirstOutEdges(nodeId) {
-			outEdge := kp.graph.GetOutEdge(outEdgeIDx)
-			adjs[id][outEdge.ToNodeID] = true
-			if !inCell[outEdge.ToNodeID] || nodeId == outEdge.ToNodeID {
-				continue
-			}
-			nodeEdges[id] = append(nodeEdges[id], newEdge(originalNodeIdToKaffpaNodeId[outEdge.ToNodeID], outEdge.Weight))
-		}
-
-		for _, inEdgeIDx := range kp.graph.GetNodeFirstInEdges(nodeId) {
-			inEdge := kp.graph.GetInEdge(inEdgeIDx)
-			if !inCell[inEdge.ToNodeID] || nodeId == inEdge.ToNodeID {
-				continue
-			}
-			if _, found := adjs[id][inEdge.ToNodeID]; found {
-				continue
-			}
-			adjs[id][inEdge.ToNodeID] = true
-			nodeEdges[id] = append(nodeEdges[id], newEdge(originalNodeIdToKaffpaNodeId[inEdge.ToNodeID], inEdge.Weight))
-		}
-	}
-
-	edgeCounter := 0
-	for _, edges := range nodeEdges {
-		edgeCounter += len(edges)
-	}
 
 	writer := bufio.NewWriter(file)
-	_, err = writer.WriteString(fmt.Sprintf("%d %d %d\n", len(kp.nodeIds), int(edgeCounter/2), 1))
-	if err != nil {
-		return err
+	nodeWeights := kp.cell.GetTotalNodeWeight() != kp.cell.GetNodeCount()
+	format := 1
+	if nodeWeights {
+		format = 11
 	}
-
-	err = writer.Flush()
+	_, err = writer.WriteString(fmt.Sprintf("%d %d %d\n", kp.cell.GetNodeCount(), kp.cell.GetEdgeCount(), format))
 	if err != nil {
 		return err
 	}
 
-	for vId, edges := range nodeEdges {
-
-		for eIdx, edge := range edges {
-			_, err = writer.WriteString(fmt.Sprintf("%d %v", edge.to, int(edge.weight*100+1)))
-			if err != nil {
-				return err
-			}
-			if eIdx < len(edges)-1 {
-				_, err = writer.WriteString(" ")
-				if err != nil {
-					return err
-				}
-			}
+	for u := int32(0); u < int32(kp.cell.GetNodeCount()); u++ {
+		first := true
+		if nodeWeights {
+			writer.WriteString(strconv.Itoa(int(kp.cell.GetNodeWeight(u))))
+			first = false
 		}
-		if vId < len(nodeEdges)-1 || (vId == len(nodeEdges)-1 && len(edges) == 0) {
-			_, err = writer.WriteString("\n")
-			if err != nil {
-				return err
+		kp.cell.ForEdges(u, func(v int32, weight int32, count int32) {
+			if !first {
+				writer.WriteString(" ")
 			}
+			first = false
+			writer.WriteString(fmt.Sprintf("%d %d", v+1, weight))
+		})
+		_, err = writer.WriteString("\n")
+		if err != nil {
+			return err
 		}
 	}
 
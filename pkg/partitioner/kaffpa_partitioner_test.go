@@ -0,0 +1,84 @@
+package partitioner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/importer"
+)
+
+// a 3x3 grid plus the diagonal between nodes 2 and 4, weights in minutes.
+const roundTripGraph = `9 13 1
+2 1.5 4 0.25
+1 1.5 3 2 4 0.5 5 3.75
+2 2 6 1
+1 0.25 2 0.5 5 1 7 4
+2 3.75 4 1 6 0.5 8 2.5
+3 1 5 0.5 9 0.75
+4 4 8 1.25
+5 2.5 7 1.25 9 3
+6 0.75 8 3
+`
+
+// TestKaffpaInputRoundTrip writes the kaffpa input of a cell graph with every edge weight mode and checks that
+// LoadMetis reads back the same edges and weights.
+func TestKaffpaInputRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.graph")
+	if err := os.WriteFile(source, []byte(roundTripGraph), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := importer.LoadMetis(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []EdgeWeightMode{EDGE_WEIGHT_UNIFORM, EDGE_WEIGHT_TIME, EDGE_WEIGHT_DISTANCE, EDGE_WEIGHT_ROAD_CLASS} {
+		cg := NewCellGraph(graph, graph.GetNodeIDs(), mode)
+		// merging the nodes of the left column makes the node weights differ, so the file is written in format 11
+		contracted := cg.Contract([]int32{0, 1, 2, 0, 3, 4, 0, 5, 6}, 7)
+
+		for _, c := range []struct {
+			name string
+			cell *CellGraph
+		}{{"plain", cg}, {"contracted", contracted}} {
+			filename := filepath.Join(dir, string(mode)+"_"+c.name)
+			if err := newKaffpaPartitioner(c.cell, 0).saveGraphToFile(filename); err != nil {
+				t.Fatal(err)
+			}
+			imported, err := importer.LoadMetis(filename+".graph", "")
+			if err != nil {
+				t.Fatalf("%s %s: %v", mode, c.name, err)
+			}
+			checkSameEdges(t, string(mode)+" "+c.name, c.cell, imported)
+		}
+	}
+}
+
+// checkSameEdges checks that graph has exactly the edges of cg with the same weights.
+func checkSameEdges(t *testing.T, name string, cg *CellGraph, graph *datastructure.Graph) {
+	t.Helper()
+	if graph.GetNodeCount() != cg.GetNodeCount() {
+		t.Fatalf("%s: %d nodes, expected %d", name, graph.GetNodeCount(), cg.GetNodeCount())
+	}
+	for u := int32(0); u < int32(cg.GetNodeCount()); u++ {
+		want := make(map[int32]float64)
+		cg.ForEdges(u, func(v int32, weight int32, count int32) {
+			want[v] = float64(weight)
+		})
+		got := make(map[int32]float64)
+		graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			got[edge.ToNodeID] = edge.Weight
+		})
+		if len(got) != len(want) {
+			t.Fatalf("%s: node %d has %d neighbors, expected %d", name, u, len(got), len(want))
+		}
+		for v, weight := range want {
+			if got[v] != weight {
+				t.Fatalf("%s: edge %d -> %d has weight %v, expected %v", name, u, v, got[v], weight)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package partitioner
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MultilevelPartition is the nested cell assignment of every node, as stored in a .mlp file.
+// the cell ids of all levels are packed into one 64 bit cell number per node,
+// the rightmost bits contain the level 0 cell id and the leftmost bits the level l-1 cell id.
+type MultilevelPartition struct {
+	numCells    []int    // number of cells in each level
+	cellNumbers []uint64 // packed cell ids of each node
+	pvOffset    []int    // bit offset of each level in the cell numbers
+}
+
+func NewMultilevelPartition(numCells []int, cellNumbers []uint64) *MultilevelPartition {
+	pvOffset := make([]int, len(numCells)+1)
+	for i := 0; i < len(numCells); i++ {
+		pvOffset[i+1] = pvOffset[i] + cellIdBits(numCells[i])
+	}
+	return &MultilevelPartition{
+		numCells:    numCells,
+		cellNumbers: cellNumbers,
+		pvOffset:    pvOffset,
+	}
+}
+
+// newMultilevelPartitionFromCells packs the cells of each level (overlayNodes) into cell numbers.
+func newMultilevelPartitionFromCells(overlayNodes [][][]int32, nodeCount int) *MultilevelPartition {
+	numCells := make([]int, len(overlayNodes))
+	for i := range overlayNodes {
+		numCells[i] = len(overlayNodes[i])
+	}
+
+	mlp := NewMultilevelPartition(numCells, make([]uint64, nodeCount))
+	for l := range overlayNodes {
+		for cellId, vertexIds := range overlayNodes[l] {
+			for _, vertexId := range vertexIds {
+				mlp.cellNumbers[vertexId] |= uint64(cellId) << uint64(mlp.pvOffset[l])
+			}
+		}
+	}
+	return mlp
+}
+
+// cellIdBits returns ceil(log2(numCells)), the number of bits needed to represent a cell id in a level with numCells cells.
+func cellIdBits(numCells int) int {
+	if numCells <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log2(float64(numCells))))
+}
+
+func (p *MultilevelPartition) GetLevelCount() int {
+	return len(p.numCells)
+}
+
+func (p *MultilevelPartition) GetNodeCount() int {
+	return len(p.cellNumbers)
+}
+
+func (p *MultilevelPartition) GetCellCount(level int) int {
+	return p.numCells[level]
+}
+
+func (p *MultilevelPartition) GetCellNumber(nodeID int32) uint64 {
+	return p.cellNumbers[nodeID]
+}
+
+// GetCellID returns the id of the cell containing nodeID at the given level.
+func (p *MultilevelPartition) GetCellID(level int, nodeID int32) int32 {
+	bits := p.pvOffset[level+1] - p.pvOffset[level]
+	return int32((p.cellNumbers[nodeID] >> uint64(p.pvOffset[level])) & (1<<uint64(bits) - 1))
+}
+
+// CellAssignment returns the cell id of every node at the given level.
+func (p *MultilevelPartition) CellAssignment(level int) []int32 {
+	cellOf := make([]int32, len(p.cellNumbers))
+	for i := range cellOf {
+		cellOf[i] = p.GetCellID(level, int32(i))
+	}
+	return cellOf
+}
+
+// GetCells returns the nodes of every cell at the given level.
+func (p *MultilevelPartition) GetCells(level int) [][]int32 {
+	cells := make([][]int32, p.numCells[level])
+	for nodeID := range p.cellNumbers {
+		cellId := p.GetCellID(level, int32(nodeID))
+		cells[cellId] = append(cells[cellId], int32(nodeID))
+	}
+	return cells
+}
+
+// WriteToFile writes the partition as a .mlp file: the number of levels, the number of cells in each level,
+// the number of nodes and the cell number of each node, one value per line.
+func (p *MultilevelPartition) WriteToFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%d\n", len(p.numCells))
+	for _, numCells := range p.numCells {
+		fmt.Fprintf(w, "%d\n", numCells)
+	}
+	fmt.Fprintf(w, "%d\n", len(p.cellNumbers))
+	for _, cellNumber := range p.cellNumbers {
+		fmt.Fprintf(w, "%d\n", cellNumber)
+	}
+	return w.Flush()
+}
+
+// ReadMLPFile reads a partition written by WriteToFile.
+func ReadMLPFile(filename string) (*MultilevelPartition, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	readUint := func() (uint64, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, fmt.Errorf("%s: unexpected end of file after line %d", filename, lineNum)
+		}
+		lineNum++
+		v, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s:%d: %w", filename, lineNum, err)
+		}
+		return v, nil
+	}
+
+	levels, err := readUint()
+	if err != nil {
+		return nil, err
+	}
+	if levels > 64 {
+		// checked before allocating, a multilevel partition never has more levels than bits in its cell numbers
+		return nil, fmt.Errorf("%s: %d levels, expected at most 64", filename, levels)
+	}
+	numCells := make([]int, levels)
+	for i := range numCells {
+		v, err := readUint()
+		if err != nil {
+			return nil, err
+		}
+		numCells[i] = int(v)
+	}
+
+	nodeCount, err := readUint()
+	if err != nil {
+		return nil, err
+	}
+	// the node count is not trusted for the allocation, a truncated file fails before the slice grows past its size
+	cellNumbers := make([]uint64, 0)
+	for i := uint64(0); i < nodeCount; i++ {
+		cellNumber, err := readUint()
+		if err != nil {
+			return nil, err
+		}
+		cellNumbers = append(cellNumbers, cellNumber)
+	}
+
+	mlp := NewMultilevelPartition(numCells, cellNumbers)
+	if mlp.pvOffset[len(numCells)] > 64 {
+		return nil, fmt.Errorf("%s: cell ids of %d levels do not fit in 64 bits", filename, levels)
+	}
+	for level := range numCells {
+		for nodeID := range cellNumbers {
+			if int(mlp.GetCellID(level, int32(nodeID))) >= numCells[level] {
+				return nil, fmt.Errorf("%s: node %d has cell id out of range in level %d", filename, nodeID, level)
+			}
+		}
+	}
+	return mlp, nil
+}
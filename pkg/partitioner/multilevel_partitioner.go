@@ -1,15 +1,11 @@
 package partitioner
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"os"
-	"time"
 
 	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
-	"golang.org/x/exp/rand"
 )
 
 type MulitlevelPartitioner struct {
@@ -19,29 +15,122 @@ type MulitlevelPartitioner struct {
 	l            int         // max level of overlay graph
 	overlayNodes [][][]int32 // nodes in each cells in each level
 	graph        *datastructure.Graph
+	seed         int64 // seed of every randomized step, runs with the same seed and input write identical .mlp files
+
+	partitionerName    string // prefix of every file written by Run
+	newCellPartitioner CellPartitionerFactory
+	edgeWeightMode     EdgeWeightMode
+
+	driverMode       DriverMode
+	refinementRounds int // rounds of label propagation after every level, 0 disables refinement
+	refinements      []RefinementReport
 }
 
-func NewMultilevelPartitioner(u []int, l int, graph *datastructure.Graph) *MulitlevelPartitioner {
+func NewMultilevelPartitioner(u []int, l int, graph *datastructure.Graph, seed int64) *MulitlevelPartitioner {
 	if len(u) != l {
 		panic(fmt.Sprintf("cell levels %d and cell array size %d must be the same", l, len(u)))
 	}
 	return &MulitlevelPartitioner{
-		u:            u,
-		l:            l,
-		overlayNodes: make([][][]int32, l),
-		graph:        graph,
+		u:              u,
+		l:              l,
+		overlayNodes:   make([][][]int32, l),
+		graph:          graph,
+		seed:           seed,
+		edgeWeightMode: EDGE_WEIGHT_TIME,
+		driverMode:     DRIVER_MODE_TOP_DOWN,
 	}
 }
 
+// SetEdgeWeightMode sets the weight of the cut edges minimized by the cell partitioners, EDGE_WEIGHT_TIME by default.
+func (mp *MulitlevelPartitioner) SetEdgeWeightMode(mode EdgeWeightMode) {
+	mp.edgeWeightMode = mode
+}
+
+// SetCellPartitioner sets the partitioner of every cell used by Run, name is the prefix of every file written by Run.
+func (mp *MulitlevelPartitioner) SetCellPartitioner(name string, factory CellPartitionerFactory) {
+	mp.partitionerName = name
+	mp.newCellPartitioner = factory
+}
+
+// SetDriverMode sets the order in which Run builds the levels, DRIVER_MODE_TOP_DOWN by default.
+func (mp *MulitlevelPartitioner) SetDriverMode(mode DriverMode) {
+	mp.driverMode = mode
+}
+
+// SetRefinementRounds enables the refinement of every level after it was partitioned with up to rounds rounds
+// of size-constrained label propagation, 0 disables refinement.
+func (mp *MulitlevelPartitioner) SetRefinementRounds(rounds int) {
+	mp.refinementRounds = rounds
+}
+
+// GetRefinementReports returns the quality before and after the refinement of every refined level of the last run,
+// from the highest level to the lowest.
+func (mp *MulitlevelPartitioner) GetRefinementReports() []RefinementReport {
+	return mp.refinements
+}
+
+// RunMLPKaffpa partitions every level with kaffpa.
 func (mp *MulitlevelPartitioner) RunMLPKaffpa(name string) error {
-	// start from highest level
+	factory, err := NewCellPartitionerFactory(CELL_PARTITIONER_KAFFPA)
+	if err != nil {
+		return err
+	}
+	mp.SetCellPartitioner(CELL_PARTITIONER_KAFFPA, factory)
+	return mp.Run(name)
+}
+
+// Run partitions the graph with the cell partitioner in the driver mode (top down by default), refines every level
+// after it was built if refinement rounds are set and writes <partitioner>_<name>.mlp, the geojson of every level
+// (cells_<partitioner>_<name>_level_<level>.geojson and cut_edges_<partitioner>_<name>_level_<level>.geojson) and,
+// if levels were refined, the refinement reports to <partitioner>_<name>_refinement.json.
+func (mp *MulitlevelPartitioner) Run(name string) error {
+	mp.overlayNodes = make([][][]int32, mp.l)
+	mp.refinements = mp.refinements[:0]
+
+	var err error
+	switch mp.driverMode {
+	case DRIVER_MODE_BOTTOM_UP:
+		err = mp.runBottomUp(name, mp.l-1)
+	case DRIVER_MODE_HYBRID:
+		err = mp.partitionTopLevel(name)
+		if err == nil {
+			err = mp.runBottomUp(name, mp.l-2)
+		}
+	default:
+		err = mp.runTopDown(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// every output file of the run has the same prefix, so runs of different partitioners do not overwrite each other
+	prefix := fmt.Sprintf("%s_%s", mp.partitionerName, name)
+	partition := mp.GetPartition()
+	for level := mp.l - 1; level >= 0; level-- {
+		if err := mp.saveCellsToFile(partition, prefix, level); err != nil {
+			return err
+		}
+		if err := mp.saveCutEdgesToFile(partition, prefix, level); err != nil {
+			return err
+		}
+	}
+	if len(mp.refinements) > 0 {
+		if err := WriteRefinementReports(prefix+"_refinement.json", mp.refinements); err != nil {
+			return err
+		}
+	}
+	return mp.writeMLPToMLPFile(prefix + ".mlp")
+}
+
+// partitionTopLevel partitions the whole graph into cells with at most u[l-1] nodes.
+func (mp *MulitlevelPartitioner) partitionTopLevel(name string) error {
 	nodeIDs := mp.graph.GetNodeIDs()
 
 	// partitions original graph into cells with size <= u[l-1]
 	log.Printf("partitioning level %d with max cell size %d", mp.l-1, mp.u[mp.l-1])
 	if len(nodeIDs) > mp.u[mp.l-1] {
-		kaffpa := newKaffpaPartitioner(mp.graph, nodeIDs)
-		partitions, err := kaffpa.partitionCell(mp.l-1, 0, name, mp.u[mp.l-1])
+		cp := mp.newCellPartitioner(NewCellGraph(mp.graph, nodeIDs, mp.edgeWeightMode), mp.seed)
+		partitions, err := cp.PartitionCell(mp.l-1, 0, name, mp.u[mp.l-1])
 		if err != nil {
 			return err
 		}
@@ -49,15 +138,27 @@ func (mp *MulitlevelPartitioner) RunMLPKaffpa(name string) error {
 	} else {
 		mp.overlayNodes[mp.l-1] = [][]int32{nodeIDs}
 	}
+	if mp.refinementRounds > 0 {
+		mp.refineLevel(mp.l - 1)
+	}
 	log.Printf("level %d done, total cells: %d", mp.l-1, len(mp.overlayNodes[mp.l-1]))
+	return nil
+}
+
+// runTopDown partitions the whole graph into cells of at most u[l-1] nodes, then every cell of level i+1
+// into cells of at most u[i] nodes.
+func (mp *MulitlevelPartitioner) runTopDown(name string) error {
+	if err := mp.partitionTopLevel(name); err != nil {
+		return err
+	}
 
 	// next partition each cell in previous level
 	for level := mp.l - 2; level >= 0; level-- {
 		log.Printf("partitioning level %d with max cell size %d", level, mp.u[level])
 		for cellId, cell := range mp.overlayNodes[level+1] {
 			log.Printf("partitioning cell %d in level %d", cellId, level+1)
-			kaffpa := newKaffpaPartitioner(mp.graph, cell)
-			partitions, err := kaffpa.partitionCell(level, cellId, name, mp.u[level])
+			cp := mp.newCellPartitioner(NewCellGraph(mp.graph, cell, mp.edgeWeightMode), mp.seed)
+			partitions, err := cp.PartitionCell(level, cellId, name, mp.u[level])
 			if err != nil {
 				return err
 			}
@@ -66,33 +167,21 @@ func (mp *MulitlevelPartitioner) RunMLPKaffpa(name string) error {
 
 		}
 
+		if mp.refinementRounds > 0 {
+			mp.refineLevel(level)
+		}
 		log.Printf("level %d done, total cells: %d", level, len(mp.overlayNodes[level]))
-		mp.savePartitionsToFile(mp.overlayNodes[level], mp.graph, name, level)
 	}
-	return mp.writeMLPToMLPFile(fmt.Sprintf("kaffpa_%s.mlp", name))
+	return nil
 }
 
-func (mp *MulitlevelPartitioner) writeMLPToMLPFile(filename string) error {
-
-	numCells := make([]int, mp.l)
-	for i := 0; i < mp.l; i++ {
-		numCells[i] = len(mp.overlayNodes[i])
-	}
-
-	pvOffset := make([]int, mp.l+1)
-	for i := 0; i < mp.l; i++ {
-		pvOffset[i+1] = pvOffset[i] + int(math.Ceil(math.Log2(float64(numCells[i])))) // ceil(log2(numCells[i])) = number of bits needed to represent cell id in level-i
-	}
-
-	cellNumbers := make([]uint64, len(mp.graph.GetNodeIDs())) // 64 bit integer. rightmost contain level 0 cellId, leftmost contain level l-1 cellId
+// GetPartition returns the nested cell assignment computed by the last run.
+func (mp *MulitlevelPartitioner) GetPartition() *MultilevelPartition {
+	return newMultilevelPartitionFromCells(mp.overlayNodes, mp.graph.GetNodeCount())
+}
 
-	for l := 0; l < mp.l; l++ {
-		for cellId, vertexIds := range mp.overlayNodes[l] {
-			for _, vertexId := range vertexIds {
-				cellNumbers[vertexId] |= uint64(cellId) << uint64(pvOffset[l])
-			}
-		}
-	}
+func (mp *MulitlevelPartitioner) writeMLPToMLPFile(filename string) error {
+	partition := mp.GetPartition()
 
 	overlayEdgeCount := 0
 	nonOverlayEdgeCount := 0
@@ -102,7 +191,7 @@ func (mp *MulitlevelPartitioner) writeMLPToMLPFile(filename string) error {
 			e := mp.graph.GetOutEdge(eId)
 			v := e.ToNodeID
 
-			if cellNumbers[u.ID] != cellNumbers[v] {
+			if partition.GetCellNumber(u.ID) != partition.GetCellNumber(v) {
 				overlayEdgeCount++
 			} else {
 				nonOverlayEdgeCount++
@@ -112,73 +201,25 @@ func (mp *MulitlevelPartitioner) writeMLPToMLPFile(filename string) error {
 
 	log.Printf("overlayEdgeCount: %d, nonOverlayEdgeCount: %d", overlayEdgeCount, nonOverlayEdgeCount)
 
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-
-	defer f.Close()
-
-	_, err = f.WriteString(fmt.Sprintf("%d\n", len(numCells)))
-	if err != nil {
-		return err
-	}
-
-	for i := 0; i < len(numCells); i++ {
-		_, err := f.WriteString(fmt.Sprintf("%d\n", numCells[i]))
-		if err != nil {
-			return err
-		}
-	}
+	return partition.WriteToFile(filename)
+}
 
-	_, err = f.WriteString(fmt.Sprintf("%d\n", len(mp.graph.GetNodeIDs())))
+// saveCellsToFile writes the cell polygons of the level to cells_<prefix>_level_<level>.geojson.
+func (mp *MulitlevelPartitioner) saveCellsToFile(partition *MultilevelPartition, prefix string, level int) error {
+	f, err := os.Create(fmt.Sprintf("cells_%s_level_%d.geojson", prefix, level))
 	if err != nil {
 		return err
 	}
-
-	for _, vertexID := range mp.graph.GetNodeIDs() {
-		_, err := f.WriteString(fmt.Sprintf("%d\n", cellNumbers[vertexID]))
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	defer f.Close()
+	return WriteCellsGeoJSON(f, mp.graph, partition, level)
 }
 
-func (mp *MulitlevelPartitioner) savePartitionsToFile(partitions [][]int32, graph *datastructure.Graph,
-	name string, level int) error {
-	type partitionType struct {
-		Nodes []datastructure.Coordinate `json:"nodes"`
-	}
-	nodes := make([]int, len(graph.GetNodes()))
-
-	parts := []partitionType{}
-	for partitionID, partition := range partitions {
-		rand.Seed(uint64(time.Now().UnixNano()))
-		rand.Shuffle(len(partition), func(i, j int) { partition[i], partition[j] = partition[j], partition[i] })
-		partitionNodes := make([]datastructure.Coordinate, 0)
-
-		for i := 0; i < int(float64(len(partition))*0.3); i++ {
-			node := graph.GetNode(partition[i])
-			partitionNodes = append(partitionNodes, datastructure.NewCoordinate(
-				node.Lat, node.Lon,
-			))
-		}
-		parts = append(parts, partitionType{
-			Nodes: partitionNodes,
-		})
-
-		for _, nodeID := range partition {
-			nodes[nodeID] = partitionID
-		}
-	}
-	buf, err := json.MarshalIndent(parts, "", "  ")
+// saveCutEdgesToFile writes the cut edges and boundary vertices of the level to cut_edges_<prefix>_level_<level>.geojson.
+func (mp *MulitlevelPartitioner) saveCutEdgesToFile(partition *MultilevelPartition, prefix string, level int) error {
+	f, err := os.Create(fmt.Sprintf("cut_edges_%s_level_%d.geojson", prefix, level))
 	if err != nil {
 		return err
 	}
-
-	if err := os.WriteFile(fmt.Sprintf("nodePerPartitions_%s_level_%v.json", name, level), buf, 0644); err != nil {
-		return err
-	}
-	return nil
+	defer f.Close()
+	return WriteCutEdgesGeoJSON(f, mp.graph, partition, level)
 }
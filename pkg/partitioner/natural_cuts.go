@@ -0,0 +1,181 @@
+package partitioner
+
+import (
+	"log"
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/flow"
+	"golang.org/x/exp/rand"
+)
+
+const (
+	naturalCutsAlpha     = 0.5  // bfs trees grow up to naturalCutsAlpha * cellSize road network nodes
+	naturalCutsCoreRatio = 10.0 // the core of a tree is its first 1/naturalCutsCoreRatio
+)
+
+// NaturalCutsPartitioner is the filter phase of PUNCH (delling et al.): it repeatedly picks a random center not yet
+// in a core, grows a bfs tree around it and cuts the tree core from the ring of nodes around the tree with a minimum cut.
+// the edges of all these natural cuts are removed, the remaining connected components (fragments) are contracted
+// and the fragment graph is partitioned by the inner cell partitioner.
+type NaturalCutsPartitioner struct {
+	cell  *CellGraph
+	seed  int64
+	inner CellPartitionerFactory
+
+	network *flow.Network // reused by the minimum cut of every tree
+	core    []int32
+}
+
+// WithNaturalCuts returns a factory that runs the natural cuts filter before the inner cell partitioner.
+func WithNaturalCuts(inner CellPartitionerFactory) CellPartitionerFactory {
+	return func(cell *CellGraph, seed int64) CellPartitioner {
+		return &NaturalCutsPartitioner{
+			cell:    cell,
+			seed:    seed,
+			inner:   inner,
+			network: flow.NewNetwork(0),
+			core:    make([]int32, 0),
+		}
+	}
+}
+
+func (nc *NaturalCutsPartitioner) PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
+	if nc.cell.GetTotalNodeWeight() <= cellSize {
+		return nc.inner(nc.cell, nc.seed).PartitionCell(level, cellId, name, cellSize)
+	}
+
+	fragmentOf, fragmentCount := nc.fragments(int32(math.Max(1, naturalCutsAlpha*float64(cellSize))))
+	fragments := nc.cell.Contract(fragmentOf, fragmentCount)
+	log.Printf("natural cuts: %d nodes, %d edges -> %d fragments, %d edges", nc.cell.GetNodeCount(), nc.cell.GetEdgeCount(),
+		fragments.GetNodeCount(), fragments.GetEdgeCount())
+
+	return nc.inner(fragments, nc.seed).PartitionCell(level, cellId, name, cellSize)
+}
+
+// fragments returns the fragment of every node and the number of fragments,
+// the trees grow up to treeSize node weight.
+func (nc *NaturalCutsPartitioner) fragments(treeSize int32) ([]int32, int) {
+	cg := nc.cell
+	n := cg.GetNodeCount()
+	coreSize := int32(math.Max(1, float64(treeSize)/naturalCutsCoreRatio))
+
+	isCut := make([]bool, len(cg.edgeHead))
+	covered := make([]bool, n)
+	treeIndex := make([]int32, n) // position of every node in the current tree, -1 outside of it
+	for i := range treeIndex {
+		treeIndex[i] = -1
+	}
+
+	rng := rand.New(rand.NewSource(uint64(nc.seed)))
+	tree := make([]int32, 0)
+	for _, center := range rng.Perm(n) {
+		if covered[center] {
+			continue
+		}
+
+		// grow the bfs tree, the first nodes up to coreSize weight are the core
+		tree = append(tree[:0], int32(center))
+		treeIndex[center] = 0
+		weight := cg.nodeWeight[center]
+		coreEnd := 1
+		for head := 0; head < len(tree) && weight < treeSize; head++ {
+			u := tree[head]
+			for e := cg.firstEdge[u]; e < cg.firstEdge[u+1] && weight < treeSize; e++ {
+				v := cg.edgeHead[e]
+				if treeIndex[v] != -1 {
+					continue
+				}
+				treeIndex[v] = int32(len(tree))
+				tree = append(tree, v)
+				weight += cg.nodeWeight[v]
+				if weight-cg.nodeWeight[v] < coreSize && coreEnd == len(tree)-1 {
+					coreEnd = len(tree)
+				}
+			}
+		}
+		for _, u := range tree[:coreEnd] {
+			covered[u] = true
+		}
+
+		nc.cutTree(tree, coreEnd, treeIndex, isCut)
+		for _, u := range tree {
+			treeIndex[u] = -1
+		}
+	}
+
+	// fragments are the connected components without the cut edges
+	fragmentOf := make([]int32, n)
+	for i := range fragmentOf {
+		fragmentOf[i] = -1
+	}
+	fragmentCount := 0
+	queue := make([]int32, 0)
+	for s := int32(0); s < int32(n); s++ {
+		if fragmentOf[s] != -1 {
+			continue
+		}
+		fragmentOf[s] = int32(fragmentCount)
+		queue = append(queue[:0], s)
+		for head := 0; head < len(queue); head++ {
+			u := queue[head]
+			for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+				if v := cg.edgeHead[e]; !isCut[e] && fragmentOf[v] == -1 {
+					fragmentOf[v] = int32(fragmentCount)
+					queue = append(queue, v)
+				}
+			}
+		}
+		fragmentCount++
+	}
+	return fragmentOf, fragmentCount
+}
+
+// cutTree computes the minimum cut between the core tree[:coreEnd] and the ring (nodes adjacent to the tree)
+// with edge counts as capacities and marks both directions of the cut edges in isCut.
+// trees without a ring cover their whole component and are not cut.
+func (nc *NaturalCutsPartitioner) cutTree(tree []int32, coreEnd int, treeIndex []int32, isCut []bool) {
+	cg := nc.cell
+	sink := int32(len(tree))
+	network := nc.network
+	network.Reset(len(tree) + 1)
+
+	nc.core = nc.core[:0]
+	hasRing := false
+	for i, u := range tree {
+		if i < coreEnd {
+			nc.core = append(nc.core, int32(i))
+		}
+		for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+			j := treeIndex[cg.edgeHead[e]]
+			switch {
+			case j == -1:
+				hasRing = true
+				network.AddEdge(int32(i), sink, int64(cg.edgeCount[e]), 0)
+			case int32(i) < j:
+				network.AddEdge(int32(i), j, int64(cg.edgeCount[e]), int64(cg.edgeCount[e]))
+			}
+		}
+	}
+	if !hasRing {
+		return
+	}
+
+	network.Dinic(nc.core, []int32{sink})
+	sourceSide := network.SourceSide()
+	for i, u := range tree {
+		if !sourceSide[i] {
+			continue
+		}
+		for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+			v := cg.edgeHead[e]
+			if j := treeIndex[v]; j == -1 || !sourceSide[j] {
+				isCut[e] = true
+				for r := cg.firstEdge[v]; r < cg.firstEdge[v+1]; r++ {
+					if cg.edgeHead[r] == u {
+						isCut[r] = true
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package partitioner
+
+import (
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+)
+
+// LevelQuality summarizes the cells of one partition level.
+type LevelQuality struct {
+	Level            int     `json:"level"`
+	NumCells         int     `json:"num_cells"`
+	CutEdges         int     `json:"cut_edges"`         // edges whose endpoints are in different cells
+	BoundaryVertices int     `json:"boundary_vertices"` // nodes with an in or out edge to another cell
+	MinCellSize      int     `json:"min_cell_size"`
+	MaxCellSize      int     `json:"max_cell_size"`
+	AvgCellSize      float64 `json:"avg_cell_size"`
+}
+
+// ComputeLevelQuality computes the cut and cell size statistics of one level for the cell assignment cellOf.
+func ComputeLevelQuality(graph *datastructure.Graph, level int, cellOf []int32, numCells int) LevelQuality {
+	q := LevelQuality{
+		Level:    level,
+		NumCells: numCells,
+	}
+
+	cellSizes := make([]int, numCells)
+	for _, cellId := range cellOf {
+		if cellId >= 0 {
+			cellSizes[cellId]++
+		}
+	}
+	q.MinCellSize = math.MaxInt
+	for _, size := range cellSizes {
+		q.MinCellSize = min(q.MinCellSize, size)
+		q.MaxCellSize = max(q.MaxCellSize, size)
+	}
+	if numCells == 0 {
+		q.MinCellSize = 0
+	} else {
+		q.AvgCellSize = float64(len(cellOf)) / float64(numCells)
+	}
+
+	for _, edge := range graph.GraphStorage.EdgeStorage {
+		if cellOf[edge.FromNodeID] != cellOf[edge.ToNodeID] {
+			q.CutEdges++
+		}
+	}
+	for nodeID := range cellOf {
+		if isBoundaryVertex(graph, int32(nodeID), cellOf) {
+			q.BoundaryVertices++
+		}
+	}
+	return q
+}
+
+// ComputeQuality computes the quality of every level of the partition.
+func ComputeQuality(graph *datastructure.Graph, partition *MultilevelPartition) []LevelQuality {
+	report := make([]LevelQuality, partition.GetLevelCount())
+	for level := range report {
+		report[level] = ComputeLevelQuality(graph, level, partition.CellAssignment(level), partition.GetCellCount(level))
+	}
+	return report
+}
+
+// isBoundaryVertex returns true if nodeID has an in or out edge to a node in another cell.
+func isBoundaryVertex(graph *datastructure.Graph, nodeID int32, cellOf []int32) bool {
+	for _, v := range graph.GetOutNeighbors(nodeID) {
+		if cellOf[v] != cellOf[nodeID] {
+			return true
+		}
+	}
+	for _, v := range graph.GetInNeighbors(nodeID) {
+		if cellOf[v] != cellOf[nodeID] {
+			return true
+		}
+	}
+	return false
+}
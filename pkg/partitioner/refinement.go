@@ -0,0 +1,172 @@
+package partitioner
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"golang.org/x/exp/rand"
+)
+
+// RefinementReport is the quality of a level before and after its refinement.
+type RefinementReport struct {
+	Level  int          `json:"level"`
+	Moves  int          `json:"moves"` // nodes (or cells of the level below) moved to another cell
+	Before LevelQuality `json:"before"`
+	After  LevelQuality `json:"after"`
+}
+
+// WriteRefinementReports writes the reports of a run as json, next to its .mlp file.
+func WriteRefinementReports(filename string, reports []RefinementReport) error {
+	buf, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf, 0o644)
+}
+
+// ReadRefinementReports reads the reports written by WriteRefinementReports.
+func ReadRefinementReports(filename string) ([]RefinementReport, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]RefinementReport, 0)
+	if err := json.Unmarshal(buf, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// refineLevel improves the cut of level with size-constrained label propagation: boundary nodes move to the adjacent
+// cell they have the largest edge weight to, if that reduces the cut, the cell stays within u[level] nodes and
+// both cells lie in the same cell of the nearest level above that was built. if the level below was built,
+// its cells move instead of single nodes.
+func (mp *MulitlevelPartitioner) refineLevel(level int) {
+	cells := mp.overlayNodes[level]
+	cellOf := make([]int32, mp.graph.GetNodeCount())
+	for cellId, cell := range cells {
+		for _, nodeId := range cell {
+			cellOf[nodeId] = int32(cellId)
+		}
+	}
+	report := RefinementReport{
+		Level:  level,
+		Before: ComputeLevelQuality(mp.graph, level, cellOf, len(cells)),
+	}
+
+	// cells only move inside their enclosing cell, if the level below was already built (bottom up) its cells
+	// move as a whole so that they stay nested
+	var lowerOf []int32
+	if level > 0 && len(mp.overlayNodes[level-1]) > 0 {
+		lowerOf = mp.cellAssignment(level - 1)
+	}
+	rng := rand.New(rand.NewSource(uint64(mp.seed)))
+	localId := make([]int32, len(cells))
+	for i := range localId {
+		localId[i] = -1
+	}
+	for _, parent := range mp.enclosingCells(level) {
+		// the cells of the parent get local ids in order of appearance
+		globalId := make([]int32, 0)
+		for _, nodeId := range parent {
+			if c := cellOf[nodeId]; localId[c] == -1 {
+				localId[c] = int32(len(globalId))
+				globalId = append(globalId, c)
+			}
+		}
+		if len(globalId) > 1 {
+			cg := NewCellGraph(mp.graph, parent, mp.edgeWeightMode)
+			if lowerOf != nil {
+				lowerId := make(map[int32]int32)
+				unitOf := make([]int32, len(parent))
+				for i, nodeId := range parent {
+					if _, ok := lowerId[lowerOf[nodeId]]; !ok {
+						lowerId[lowerOf[nodeId]] = int32(len(lowerId))
+					}
+					unitOf[i] = lowerId[lowerOf[nodeId]]
+				}
+				cg = cg.Contract(unitOf, len(lowerId))
+			}
+
+			unitCellOf := make([]int32, cg.GetNodeCount())
+			for u := range unitCellOf {
+				unitCellOf[u] = localId[cellOf[cg.GetMembers(int32(u))[0]]]
+			}
+			report.Moves += refineCells(cg, unitCellOf, len(globalId), int64(mp.u[level]), mp.refinementRounds, rng)
+			for u, c := range unitCellOf {
+				for _, nodeId := range cg.GetMembers(int32(u)) {
+					cellOf[nodeId] = globalId[c]
+				}
+			}
+		}
+		for _, c := range globalId {
+			localId[c] = -1
+		}
+	}
+
+	refined := make([][]int32, len(cells))
+	for nodeId, c := range cellOf {
+		refined[c] = append(refined[c], int32(nodeId))
+	}
+	mp.overlayNodes[level] = refined
+	report.After = ComputeLevelQuality(mp.graph, level, cellOf, len(cells))
+	mp.refinements = append(mp.refinements, report)
+	log.Printf("refined level %d: %d moves, cut edges %d -> %d, boundary vertices %d -> %d", level, report.Moves,
+		report.Before.CutEdges, report.After.CutEdges, report.Before.BoundaryVertices, report.After.BoundaryVertices)
+}
+
+// refineCells runs up to rounds rounds of size-constrained label propagation on cg, cellOf assigns every node one
+// of cellCount cells. a node moves to the neighboring cell with the largest positive gain (edge weight to that cell
+// minus edge weight to its own cell) if the cell stays within maxCellWeight, cells are never emptied.
+// stops early when a round moves no node, returns the number of moves.
+func refineCells(cg *CellGraph, cellOf []int32, cellCount int, maxCellWeight int64, rounds int, rng *rand.Rand) int {
+	cellWeight := make([]int64, cellCount)
+	for u, c := range cellOf {
+		cellWeight[c] += int64(cg.GetNodeWeight(int32(u)))
+	}
+
+	connection := make([]int64, cellCount) // edge weight from the current node to every cell, edge weights are positive
+	touched := make([]int32, 0)
+	totalMoves := 0
+	for round := 0; round < rounds; round++ {
+		moves := 0
+		for _, u := range rng.Perm(cg.GetNodeCount()) {
+			c := cellOf[u]
+			w := int64(cg.GetNodeWeight(int32(u)))
+			if cellWeight[c] == w {
+				continue
+			}
+
+			touched = touched[:0]
+			cg.ForEdges(int32(u), func(v int32, weight int32, count int32) {
+				d := cellOf[v]
+				if connection[d] == 0 {
+					touched = append(touched, d)
+				}
+				connection[d] += int64(weight)
+			})
+			best, bestGain := c, int64(0)
+			for _, d := range touched {
+				if gain := connection[d] - connection[c]; d != c && gain > bestGain && cellWeight[d]+w <= maxCellWeight {
+					best, bestGain = d, gain
+				}
+			}
+			for _, d := range touched {
+				connection[d] = 0
+			}
+
+			if best != c {
+				cellOf[u] = best
+				cellWeight[c] -= w
+				cellWeight[best] += w
+				moves++
+			}
+		}
+		totalMoves += moves
+		if moves == 0 {
+			break
+		}
+	}
+	return totalMoves
+}
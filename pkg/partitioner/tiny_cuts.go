@@ -0,0 +1,150 @@
+package partitioner
+
+import (
+	"log"
+	"math"
+)
+
+const tinyCutsMaxFraction = 0.1 // super-nodes stand for at most tinyCutsMaxFraction * cellSize road network nodes
+
+// TinyCutsPartitioner contracts the parts of a cell that are separated by tiny cuts before running the inner cell partitioner:
+// dangling trees (1-cuts) are merged into the node they hang from and chains of degree 2 nodes between two junctions (2-cuts)
+// are merged into one super-node. the weight of a super-node is the number of road network nodes it stands for,
+// the cells returned by the inner partitioner are expanded back to road network nodes by CellGraph.Expand.
+type TinyCutsPartitioner struct {
+	cell  *CellGraph
+	seed  int64
+	inner CellPartitionerFactory
+}
+
+// WithTinyCuts returns a factory that contracts tiny cuts before the inner cell partitioner.
+func WithTinyCuts(inner CellPartitionerFactory) CellPartitionerFactory {
+	return func(cell *CellGraph, seed int64) CellPartitioner {
+		return &TinyCutsPartitioner{
+			cell:  cell,
+			seed:  seed,
+			inner: inner,
+		}
+	}
+}
+
+func (tc *TinyCutsPartitioner) PartitionCell(level, cellId int, name string, cellSize int) ([][]int32, error) {
+	if tc.cell.GetTotalNodeWeight() <= cellSize {
+		return tc.inner(tc.cell, tc.seed).PartitionCell(level, cellId, name, cellSize)
+	}
+
+	groupOf, groupCount := tc.superNodes(int32(math.Max(1, tinyCutsMaxFraction*float64(cellSize))))
+	contracted := tc.cell.Contract(groupOf, groupCount)
+	log.Printf("tiny cuts: %d nodes, %d edges -> %d nodes, %d edges", tc.cell.GetNodeCount(), tc.cell.GetEdgeCount(),
+		contracted.GetNodeCount(), contracted.GetEdgeCount())
+
+	return tc.inner(contracted, tc.seed).PartitionCell(level, cellId, name, cellSize)
+}
+
+// superNodes returns the super-node of every node and the number of super-nodes, no super-node weighs more than maxWeight.
+func (tc *TinyCutsPartitioner) superNodes(maxWeight int32) ([]int32, int) {
+	cg := tc.cell
+	n := cg.GetNodeCount()
+
+	// mergedInto[u] is u for nodes that are still in the graph, otherwise the node u was merged into
+	mergedInto := make([]int32, n)
+	weight := make([]int32, n)
+	degree := make([]int32, n)
+	for u := int32(0); u < int32(n); u++ {
+		mergedInto[u] = u
+		weight[u] = cg.nodeWeight[u]
+		degree[u] = cg.firstEdge[u+1] - cg.firstEdge[u]
+	}
+	var find func(u int32) int32
+	find = func(u int32) int32 {
+		if mergedInto[u] != u {
+			mergedInto[u] = find(mergedInto[u])
+		}
+		return mergedInto[u]
+	}
+	alive := func(u int32) bool {
+		return mergedInto[u] == u
+	}
+	merge := func(u, into int32) {
+		mergedInto[u] = into
+		weight[into] += weight[u]
+	}
+
+	// 1-cuts: repeatedly merge degree 1 nodes into their only neighbor, which removes dangling trees leaf by leaf
+	queue := make([]int32, 0)
+	for u := int32(0); u < int32(n); u++ {
+		if degree[u] == 1 {
+			queue = append(queue, u)
+		}
+	}
+	for head := 0; head < len(queue); head++ {
+		u := queue[head]
+		if !alive(u) || degree[u] != 1 {
+			continue
+		}
+		v := int32(-1)
+		for e := cg.firstEdge[u]; e < cg.firstEdge[u+1]; e++ {
+			if alive(cg.edgeHead[e]) {
+				v = cg.edgeHead[e]
+				break
+			}
+		}
+		if v == -1 || weight[u]+weight[v] > maxWeight {
+			continue
+		}
+		merge(u, v)
+		degree[v]--
+		if degree[v] == 1 {
+			queue = append(queue, v)
+		}
+	}
+
+	// 2-cuts: merge every maximal chain of degree 2 nodes into its first node
+	inChain := make([]bool, n)
+	isChainNode := func(u int32) bool {
+		return alive(u) && degree[u] == 2 && !inChain[u]
+	}
+	for s := int32(0); s < int32(n); s++ {
+		if !isChainNode(s) {
+			continue
+		}
+		inChain[s] = true
+		for e := cg.firstEdge[s]; e < cg.firstEdge[s+1]; e++ {
+			prev, curr := s, cg.edgeHead[e]
+			if !alive(curr) {
+				continue
+			}
+			for isChainNode(curr) && weight[s]+weight[curr] <= maxWeight {
+				inChain[curr] = true
+				merge(curr, s)
+				next := int32(-1)
+				for f := cg.firstEdge[curr]; f < cg.firstEdge[curr+1]; f++ {
+					if w := cg.edgeHead[f]; w != prev && w != s && alive(w) {
+						next = w
+						break
+					}
+				}
+				if next == -1 {
+					break
+				}
+				prev, curr = curr, next
+			}
+		}
+	}
+
+	groupOf := make([]int32, n)
+	groupId := make([]int32, n)
+	for i := range groupId {
+		groupId[i] = -1
+	}
+	groupCount := 0
+	for u := int32(0); u < int32(n); u++ {
+		root := find(u)
+		if groupId[root] == -1 {
+			groupId[root] = int32(groupCount)
+			groupCount++
+		}
+		groupOf[u] = groupId[root]
+	}
+	return groupOf, groupCount
+}
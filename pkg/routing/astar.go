@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/geo"
+)
+
+// AStar is a unidirectional A* search. the heuristic of a node is the haversine distance to the target,
+// divided by the maximum edge speed of the graph for the travel time metric. it is admissible as long as
+// the Edge.Dist of every edge is at least the haversine distance between its endpoints, which holds for parsed osm graphs.
+// an AStar reuses its search buffers and must not be used by multiple goroutines at the same time.
+type AStar struct {
+	graph    *datastructure.Graph
+	metric   datastructure.Metric
+	maxSpeed float64 // meter per minute
+	forward  *datastructure.DijkstraSearch
+}
+
+func NewAStar(graph *datastructure.Graph, metric datastructure.Metric) *AStar {
+	maxSpeed := 0.0
+	for _, edge := range graph.GraphStorage.EdgeStorage {
+		if edge.Weight > 0 {
+			maxSpeed = max(maxSpeed, edge.GetEdgeSpeed())
+		}
+	}
+	return &AStar{
+		graph:    graph,
+		metric:   metric,
+		maxSpeed: maxSpeed,
+		forward:  datastructure.NewDijkstraSearch(graph.GetNodeCount()),
+	}
+}
+
+// ShortestPath returns the shortest path from source to target, Found is false if target cannot be reached.
+func (as *AStar) ShortestPath(source, target int32) Result {
+	defer as.forward.Reset()
+
+	to := as.graph.GetNode(target)
+	heuristic := func(nodeID int32) float64 {
+		node := as.graph.GetNode(nodeID)
+		dist := geo.CalculateHaversineDistance(node.Lat, node.Lon, to.Lat, to.Lon) * 1000
+		if as.metric == datastructure.METRIC_DISTANCE {
+			return dist
+		}
+		if as.maxSpeed == 0 {
+			return 0
+		}
+		return dist / as.maxSpeed
+	}
+	return shortestPathAStar(as.graph, as.metric, as.forward, source, target, heuristic)
+}
@@ -0,0 +1,95 @@
+package routing
+
+import (
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+// BidirectionalDijkstra runs a forward search from the source and a backward search from the target,
+// always advancing the direction with the smaller queue minimum, until the sum of both minimums reaches the best path.
+// a BidirectionalDijkstra reuses its search buffers and must not be used by multiple goroutines at the same time.
+type BidirectionalDijkstra struct {
+	graph    *datastructure.Graph
+	metric   datastructure.Metric
+	forward  *datastructure.DijkstraSearch
+	backward *datastructure.DijkstraSearch
+}
+
+func NewBidirectionalDijkstra(graph *datastructure.Graph, metric datastructure.Metric) *BidirectionalDijkstra {
+	return &BidirectionalDijkstra{
+		graph:    graph,
+		metric:   metric,
+		forward:  datastructure.NewDijkstraSearch(graph.GetNodeCount()),
+		backward: datastructure.NewDijkstraSearch(graph.GetNodeCount()),
+	}
+}
+
+// ShortestPath returns the shortest path from source to target, Found is false if target cannot be reached.
+func (bd *BidirectionalDijkstra) ShortestPath(source, target int32) Result {
+	defer func() {
+		bd.forward.Reset()
+		bd.backward.Reset()
+	}()
+
+	best := math.Inf(1)
+	meeting := int32(-1)
+	update := func(nodeID int32) {
+		if d := bd.forward.GetDist(nodeID) + bd.backward.GetDist(nodeID); d < best {
+			best = d
+			meeting = nodeID
+		}
+	}
+
+	bd.forward.Add(source, 0, 0, -1, -1)
+	bd.backward.Add(target, 0, 0, -1, -1)
+	update(source)
+
+	settled := 0
+	for !bd.forward.IsEmpty() || !bd.backward.IsEmpty() {
+		minForward, minBackward := math.Inf(1), math.Inf(1)
+		if !bd.forward.IsEmpty() {
+			minForward = bd.forward.GetMinRank()
+		}
+		if !bd.backward.IsEmpty() {
+			minBackward = bd.backward.GetMinRank()
+		}
+		if minForward+minBackward >= best {
+			break
+		}
+
+		if minForward <= minBackward {
+			u, ok := bd.forward.Next()
+			if !ok {
+				continue
+			}
+			settled++
+			bd.graph.ForOutEdges(u, func(edge datastructure.Edge) {
+				dist := bd.forward.GetDist(u) + bd.metric.Cost(edge)
+				if bd.forward.Add(edge.ToNodeID, dist, dist, u, edge.EdgeID) {
+					update(edge.ToNodeID)
+				}
+			})
+		} else {
+			u, ok := bd.backward.Next()
+			if !ok {
+				continue
+			}
+			settled++
+			bd.graph.ForInEdges(u, func(edge datastructure.Edge) {
+				dist := bd.backward.GetDist(u) + bd.metric.Cost(edge)
+				if bd.backward.Add(edge.FromNodeID, dist, dist, u, edge.EdgeID) {
+					update(edge.FromNodeID)
+				}
+			})
+		}
+	}
+
+	if meeting == -1 {
+		return Result{Cost: math.Inf(1), SettledNodes: settled}
+	}
+	path := util.ReverseG(bd.forward.PathTo(meeting))
+	path = append(path, bd.backward.PathTo(meeting)...)
+	return newResult(bd.graph, best, path, settled)
+}
@@ -0,0 +1,46 @@
+package routing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/internal/testgraph"
+	"golang.org/x/exp/rand"
+)
+
+type shortestPathSearch interface {
+	ShortestPath(source, target int32) Result
+}
+
+func TestShortestPathSearchesAgree(t *testing.T) {
+	graph := testgraph.Grid(t, 20, 1)
+	rng := rand.New(rand.NewSource(2))
+	for _, metric := range []datastructure.Metric{datastructure.METRIC_TRAVEL_TIME, datastructure.METRIC_DISTANCE} {
+		dijkstra := NewDijkstra(graph, metric)
+		searches := map[string]shortestPathSearch{
+			"bidirectional dijkstra": NewBidirectionalDijkstra(graph, metric),
+			"a*":                     NewAStar(graph, metric),
+		}
+		for query := 0; query < 500; query++ {
+			source, target := int32(rng.Intn(graph.GetNodeCount())), int32(rng.Intn(graph.GetNodeCount()))
+			want := dijkstra.ShortestPath(source, target)
+			if want.Found {
+				testgraph.CheckPath(t, graph, metric, "dijkstra", source, target, want.Path, want.Cost)
+			}
+			for name, search := range searches {
+				got := search.ShortestPath(source, target)
+				if got.Found != want.Found {
+					t.Fatalf("%s %s %d -> %d: found %v, dijkstra found %v", metric, name, source, target, got.Found, want.Found)
+				}
+				if !got.Found {
+					continue
+				}
+				if math.Abs(got.Cost-want.Cost) > 1e-6 {
+					t.Fatalf("%s %s %d -> %d: cost %f, dijkstra cost %f", metric, name, source, target, got.Cost, want.Cost)
+				}
+				testgraph.CheckPath(t, graph, metric, name, source, target, got.Path, got.Cost)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"math"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/util"
+)
+
+// Result is a shortest path found by one of the searches of this package.
+type Result struct {
+	Found        bool
+	Cost         float64 // cost of the path in the metric of the search
+	TravelTime   float64 // sum of Edge.Weight along the path, minutes
+	Distance     float64 // sum of Edge.Dist along the path, meters
+	Path         []int32 // ids of the edges of the path in GraphStorage.EdgeStorage, from source to target
+	SettledNodes int
+}
+
+// newResult sums the travel time and distance of the path.
+func newResult(graph *datastructure.Graph, cost float64, path []int32, settled int) Result {
+	result := Result{
+		Found:        true,
+		Cost:         cost,
+		Path:         path,
+		SettledNodes: settled,
+	}
+	for _, edgeID := range path {
+		edge := graph.GetOutEdge(edgeID)
+		result.TravelTime += edge.Weight
+		result.Distance += edge.Dist
+	}
+	return result
+}
+
+// Dijkstra is a unidirectional dijkstra search. bidirectional edges are traversed in both directions.
+// a Dijkstra reuses its search buffers and must not be used by multiple goroutines at the same time.
+type Dijkstra struct {
+	graph   *datastructure.Graph
+	metric  datastructure.Metric
+	forward *datastructure.DijkstraSearch
+}
+
+func NewDijkstra(graph *datastructure.Graph, metric datastructure.Metric) *Dijkstra {
+	return &Dijkstra{
+		graph:   graph,
+		metric:  metric,
+		forward: datastructure.NewDijkstraSearch(graph.GetNodeCount()),
+	}
+}
+
+// ShortestPath returns the shortest path from source to target, Found is false if target cannot be reached.
+func (d *Dijkstra) ShortestPath(source, target int32) Result {
+	defer d.forward.Reset()
+	return shortestPathAStar(d.graph, d.metric, d.forward, source, target, func(int32) float64 { return 0 })
+}
+
+// shortestPathAStar runs a unidirectional search with the node potentials, dijkstra if every potential is 0.
+func shortestPathAStar(graph *datastructure.Graph, metric datastructure.Metric, s *datastructure.DijkstraSearch, source, target int32,
+	potential func(nodeID int32) float64) Result {
+	s.Add(source, 0, potential(source), -1, -1)
+
+	settled := 0
+	for !s.IsEmpty() {
+		u, ok := s.Next()
+		if !ok {
+			continue
+		}
+		settled++
+		if u == target {
+			return newResult(graph, s.GetDist(u), util.ReverseG(s.PathTo(u)), settled)
+		}
+
+		graph.ForOutEdges(u, func(edge datastructure.Edge) {
+			v := edge.ToNodeID
+			if !s.IsSettled(v) {
+				dist := s.GetDist(u) + metric.Cost(edge)
+				s.Add(v, dist, dist+potential(v), u, edge.EdgeID)
+			}
+		})
+	}
+	return Result{Cost: math.Inf(1), SettledNodes: settled}
+}
@@ -0,0 +1,347 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+var (
+	ErrRunNotFound   = errors.New("run not found")
+	ErrLevelNotFound = errors.New("level not found")
+	ErrCellNotFound  = errors.New("cell not found")
+)
+
+// Server serves the partitions (.mlp files) found in dataDir for one graph, together with the partition viewer.
+type Server struct {
+	graph   *datastructure.Graph
+	dataDir string
+
+	mu   sync.Mutex
+	runs map[string]*run
+
+	edgeIndexOnce sync.Once
+	edgeIndex     *edgeIndex
+	tiles         *tileCache
+}
+
+// run is a loaded .mlp file. geojson layers are computed on first request and cached.
+type run struct {
+	mu        sync.Mutex
+	name      string
+	partition *partitioner.MultilevelPartition
+	levels    []levelInfo
+	cells     map[int][]byte
+	cutEdges  map[int][]byte
+
+	levelGeometry map[int]*levelGeometry
+}
+
+func NewServer(graph *datastructure.Graph, dataDir string) *Server {
+	return &Server{
+		graph:   graph,
+		dataDir: dataDir,
+		runs:    make(map[string]*run),
+		tiles:   newTileCache(tileCacheSize),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	mux.HandleFunc("GET /api/runs", s.handleRuns)
+	mux.HandleFunc("GET /api/runs/{run}/levels", s.handleLevels)
+	mux.HandleFunc("GET /api/runs/{run}/levels/{level}/cells", s.handleCells)
+	mux.HandleFunc("GET /api/runs/{run}/levels/{level}/cells/{cell}", s.handleCell)
+	mux.HandleFunc("GET /api/runs/{run}/levels/{level}/cut-edges", s.handleCutEdges)
+	mux.HandleFunc("GET /api/runs/{run}/levels/{level}/tiles/{z}/{x}/{y}", s.handleTile)
+	return mux
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	log.Printf("serving partitions from %s on http://%s", s.dataDir, addr)
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+type runInfo struct {
+	Name   string `json:"name"`
+	Levels int    `json:"levels"`
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	names, err := s.runNames()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	runs := make([]runInfo, 0, len(names))
+	for _, name := range names {
+		rn, err := s.getRun(name)
+		if err != nil {
+			// skip partitions of other graphs
+			log.Printf("skipping run %s: %v", name, err)
+			continue
+		}
+		runs = append(runs, runInfo{Name: name, Levels: rn.partition.GetLevelCount()})
+	}
+	writeJSON(w, runs)
+}
+
+// levelInfo is the quality of a level and, if the run was refined, the report of its refinement.
+type levelInfo struct {
+	partitioner.LevelQuality
+	Refinement *partitioner.RefinementReport `json:"refinement,omitempty"`
+}
+
+func (s *Server) handleLevels(w http.ResponseWriter, r *http.Request) {
+	rn, err := s.getRun(r.PathValue("run"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, rn.levels)
+}
+
+func (s *Server) handleCells(w http.ResponseWriter, r *http.Request) {
+	rn, level, err := s.getRunLevel(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	buf, err := rn.layer(rn.cells, level, func(b *bytes.Buffer) error {
+		return partitioner.WriteCellsGeoJSON(b, s.graph, rn.partition, level)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeGeoJSON(w, buf)
+}
+
+func (s *Server) handleCutEdges(w http.ResponseWriter, r *http.Request) {
+	rn, level, err := s.getRunLevel(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	buf, err := rn.layer(rn.cutEdges, level, func(b *bytes.Buffer) error {
+		return partitioner.WriteCutEdgesGeoJSON(b, s.graph, rn.partition, level)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeGeoJSON(w, buf)
+}
+
+type cellInfo struct {
+	Level               int     `json:"level"`
+	CellID              int32   `json:"cell_id"`
+	ParentCellID        int32   `json:"parent_cell_id"`
+	ChildCells          []int32 `json:"child_cells"`
+	NeighborCells       []int32 `json:"neighbor_cells"`
+	NodeCount           int     `json:"node_count"`
+	BoundaryVertexCount int     `json:"boundary_vertex_count"`
+	CutEdges            int     `json:"cut_edges"`
+}
+
+func (s *Server) handleCell(w http.ResponseWriter, r *http.Request) {
+	rn, level, err := s.getRunLevel(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	cellId, err := strconv.Atoi(r.PathValue("cell"))
+	if err != nil || cellId < 0 || cellId >= rn.partition.GetCellCount(level) {
+		writeError(w, ErrCellNotFound)
+		return
+	}
+	writeJSON(w, s.cellInfo(rn.partition, level, int32(cellId)))
+}
+
+func (s *Server) cellInfo(partition *partitioner.MultilevelPartition, level int, cellId int32) cellInfo {
+	info := cellInfo{
+		Level:        level,
+		CellID:       cellId,
+		ParentCellID: -1,
+	}
+
+	children := make(map[int32]struct{})
+	neighbors := make(map[int32]struct{})
+	for nodeID := int32(0); nodeID < int32(partition.GetNodeCount()); nodeID++ {
+		if partition.GetCellID(level, nodeID) != cellId {
+			continue
+		}
+		info.NodeCount++
+		if level+1 < partition.GetLevelCount() {
+			info.ParentCellID = partition.GetCellID(level+1, nodeID)
+		}
+		if level > 0 {
+			children[partition.GetCellID(level-1, nodeID)] = struct{}{}
+		}
+
+		boundary := false
+		for _, v := range s.graph.GetOutNeighbors(nodeID) {
+			if other := partition.GetCellID(level, v); other != cellId {
+				info.CutEdges++
+				neighbors[other] = struct{}{}
+				boundary = true
+			}
+		}
+		for _, v := range s.graph.GetInNeighbors(nodeID) {
+			if other := partition.GetCellID(level, v); other != cellId {
+				info.CutEdges++
+				neighbors[other] = struct{}{}
+				boundary = true
+			}
+		}
+		if boundary {
+			info.BoundaryVertexCount++
+		}
+	}
+
+	info.ChildCells = sortedKeys(children)
+	info.NeighborCells = sortedKeys(neighbors)
+	return info
+}
+
+// runNames returns the names of the .mlp files in the data directory.
+func (s *Server) runNames() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dataDir, "*.mlp"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".mlp"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *Server) getRun(name string) (*run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rn, ok := s.runs[name]; ok {
+		return rn, nil
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, ErrRunNotFound
+	}
+
+	partition, err := partitioner.ReadMLPFile(filepath.Join(s.dataDir, name+".mlp"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrRunNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if partition.GetNodeCount() != s.graph.GetNodeCount() {
+		return nil, fmt.Errorf("run %s has %d nodes but the graph has %d nodes", name, partition.GetNodeCount(), s.graph.GetNodeCount())
+	}
+
+	// <name>_refinement.json is written next to the .mlp file if the levels were refined
+	reports, err := partitioner.ReadRefinementReports(filepath.Join(s.dataDir, name+"_refinement.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	levels := make([]levelInfo, 0, partition.GetLevelCount())
+	for _, q := range partitioner.ComputeQuality(s.graph, partition) {
+		levels = append(levels, levelInfo{LevelQuality: q})
+	}
+	for i := range reports {
+		if level := reports[i].Level; level < len(levels) {
+			levels[level].Refinement = &reports[i]
+		}
+	}
+
+	rn := &run{
+		name:      name,
+		partition: partition,
+		levels:    levels,
+		cells:     make(map[int][]byte),
+		cutEdges:  make(map[int][]byte),
+
+		levelGeometry: make(map[int]*levelGeometry),
+	}
+	s.runs[name] = rn
+	return rn, nil
+}
+
+func (s *Server) getRunLevel(r *http.Request) (*run, int, error) {
+	rn, err := s.getRun(r.PathValue("run"))
+	if err != nil {
+		return nil, 0, err
+	}
+	level, err := strconv.Atoi(r.PathValue("level"))
+	if err != nil || level < 0 || level >= rn.partition.GetLevelCount() {
+		return nil, 0, ErrLevelNotFound
+	}
+	return rn, level, nil
+}
+
+// layer returns the cached geojson of the level, computing it with write on the first call.
+func (rn *run) layer(cache map[int][]byte, level int, write func(b *bytes.Buffer) error) ([]byte, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if buf, ok := cache[level]; ok {
+		return buf, nil
+	}
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		return nil, err
+	}
+	cache[level] = b.Bytes()
+	return cache[level], nil
+}
+
+func sortedKeys(set map[int32]struct{}) []int32 {
+	keys := make([]int32, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
+
+func writeGeoJSON(w http.ResponseWriter, buf []byte) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Write(buf)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrRunNotFound) || errors.Is(err, ErrLevelNotFound) || errors.Is(err, ErrCellNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
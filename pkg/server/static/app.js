@@ -0,0 +1,199 @@
+const map = L.map("map").setView([-7.560006569408966, 110.77455061681789], 13);
+
+L.tileLayer("https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png", {
+  attribution:
+    'Map data © <a href="https://openstreetmap.org">OpenStreetMap</a> contributors',
+  maxZoom: 19,
+}).addTo(map);
+
+const runSelect = document.getElementById("run");
+const levelSelect = document.getElementById("level");
+const compareSelect = document.getElementById("compare-run");
+const cutEdgesCheckbox = document.getElementById("cut-edges");
+const vectorTilesCheckbox = document.getElementById("vector-tiles");
+const statsDiv = document.getElementById("stats");
+const cellInfoDiv = document.getElementById("cell-info");
+
+let cellLayer = null;
+let compareLayer = null;
+let cutEdgeLayer = null;
+let fitted = false;
+
+// evenly spaced hues, cell ids are spread with a multiplicative hash so that neighboring cells get different colors.
+function cellColor(cellId) {
+  const h = (cellId * 137.508) % 360;
+  return `hsl(${h}, 70%, 55%)`;
+}
+
+async function getJSON(url) {
+  const response = await fetch(url);
+  if (!response.ok) {
+    throw new Error(`${url}: ${response.status} ${await response.text()}`);
+  }
+  return response.json();
+}
+
+function runURL(run) {
+  return `api/runs/${encodeURIComponent(run)}`;
+}
+
+async function loadRuns() {
+  const runs = await getJSON("api/runs");
+  runs.forEach((run) => {
+    runSelect.add(new Option(run.name, run.name));
+    compareSelect.add(new Option(run.name, run.name));
+  });
+  if (runs.length) {
+    await loadLevels();
+  }
+}
+
+async function loadLevels() {
+  const levels = await getJSON(`${runURL(runSelect.value)}/levels`);
+  const selected = levelSelect.value;
+  levelSelect.innerHTML = "";
+  levels.forEach((q) => {
+    levelSelect.add(new Option(`${q.level} (${q.num_cells} cells)`, q.level));
+  });
+  if (selected !== "" && selected < levels.length) {
+    levelSelect.value = selected;
+  }
+  levelSelect.levels = levels;
+  await loadLayers();
+}
+
+function showStats() {
+  const q = levelSelect.levels[levelSelect.value];
+  statsDiv.textContent =
+    `cells: ${q.num_cells}, cut edges: ${q.cut_edges}, ` +
+    `boundary vertices: ${q.boundary_vertices}, ` +
+    `cell size: ${q.min_cell_size}..${q.max_cell_size} (avg ${q.avg_cell_size.toFixed(1)})`;
+  const r = q.refinement;
+  if (r) {
+    statsDiv.textContent +=
+      `, refinement: ${r.moves} moves, cut edges ${r.before.cut_edges} -> ${r.after.cut_edges}, ` +
+      `boundary vertices ${r.before.boundary_vertices} -> ${r.after.boundary_vertices}`;
+  }
+}
+
+async function showCellInfo(run, level, cellId) {
+  const info = await getJSON(`${runURL(run)}/levels/${level}/cells/${cellId}`);
+  cellInfoDiv.style.display = "block";
+  cellInfoDiv.innerHTML =
+    `<b>${run}</b><br>` +
+    `level ${info.level}, cell ${info.cell_id}<br>` +
+    `parent cell: ${info.parent_cell_id}<br>` +
+    `nodes: ${info.node_count}<br>` +
+    `boundary vertices: ${info.boundary_vertex_count}<br>` +
+    `cut edges: ${info.cut_edges}<br>` +
+    `child cells: ${info.child_cells.length}<br>` +
+    `neighbor cells: ${info.neighbor_cells.join(", ")}`;
+}
+
+// vector tiles are rendered from the tiles endpoint, so partitions too large for one geojson response stay usable.
+function vectorTileLayer(run, level) {
+  return L.vectorGrid.protobuf(`${runURL(run)}/levels/${level}/tiles/{z}/{x}/{y}`, {
+    rendererFactory: L.canvas.tile,
+    interactive: true,
+    maxNativeZoom: 22,
+    maxZoom: 22,
+    getFeatureId: (feature) => `${feature.properties.cell_id}`,
+    vectorTileLayerStyles: {
+      cells: (properties) => {
+        const color = cellColor(properties.cell_id);
+        return { color: color, weight: 1, fill: true, fillColor: color, fillOpacity: 0.35 };
+      },
+      cut_edges: () =>
+        cutEdgesCheckbox.checked ? { color: "#d00", weight: 3 } : { weight: 0, opacity: 0 },
+      roads: (properties) => ({ color: cellColor(properties.cell_id), weight: 1.5 }),
+    },
+  }).on("click", (e) => showCellInfo(run, level, e.layer.properties.cell_id));
+}
+
+async function loadLayers() {
+  const run = runSelect.value;
+  const level = levelSelect.value;
+  showStats();
+
+  if (vectorTilesCheckbox.checked) {
+    if (cellLayer) {
+      map.removeLayer(cellLayer);
+    }
+    cellLayer = vectorTileLayer(run, level).addTo(map);
+    await loadCompareLayer();
+    await loadCutEdgeLayer();
+    return;
+  }
+
+  const cells = await getJSON(`${runURL(run)}/levels/${level}/cells`);
+  if (cellLayer) {
+    map.removeLayer(cellLayer);
+  }
+  cellLayer = L.geoJSON(cells, {
+    style: (feature) => {
+      const color = cellColor(feature.properties.cell_id);
+      return { color: color, weight: 1, fillColor: color, fillOpacity: 0.35 };
+    },
+    onEachFeature: (feature, layer) => {
+      layer.on("click", () => showCellInfo(run, level, feature.properties.cell_id));
+    },
+  }).addTo(map);
+
+  if (!fitted && cellLayer.getBounds().isValid()) {
+    map.fitBounds(cellLayer.getBounds());
+    fitted = true;
+  }
+
+  await loadCompareLayer();
+  await loadCutEdgeLayer();
+}
+
+async function loadCompareLayer() {
+  if (compareLayer) {
+    map.removeLayer(compareLayer);
+    compareLayer = null;
+  }
+  const run = compareSelect.value;
+  if (!run) {
+    return;
+  }
+  const levels = await getJSON(`${runURL(run)}/levels`);
+  const level = Math.min(levelSelect.value, levels.length - 1);
+  const cells = await getJSON(`${runURL(run)}/levels/${level}/cells`);
+  compareLayer = L.geoJSON(cells, {
+    style: { color: "#000", weight: 2, dashArray: "4 4", fill: false },
+    interactive: false,
+  }).addTo(map);
+}
+
+async function loadCutEdgeLayer() {
+  if (cutEdgeLayer) {
+    map.removeLayer(cutEdgeLayer);
+    cutEdgeLayer = null;
+  }
+  if (!cutEdgesCheckbox.checked || vectorTilesCheckbox.checked) {
+    if (vectorTilesCheckbox.checked && cellLayer) {
+      cellLayer.redraw();
+    }
+    return;
+  }
+  const run = runSelect.value;
+  const level = levelSelect.value;
+  const cutEdges = await getJSON(`${runURL(run)}/levels/${level}/cut-edges`);
+  cutEdgeLayer = L.geoJSON(cutEdges, {
+    style: { color: "#d00", weight: 3 },
+    pointToLayer: (feature, latlng) =>
+      L.circleMarker(latlng, { radius: 2, color: "#d00" }),
+  }).addTo(map);
+}
+
+runSelect.addEventListener("change", () => loadLevels().catch(console.error));
+levelSelect.addEventListener("change", () => loadLayers().catch(console.error));
+compareSelect.addEventListener("change", () => loadCompareLayer().catch(console.error));
+cutEdgesCheckbox.addEventListener("change", () => loadCutEdgeLayer().catch(console.error));
+vectorTilesCheckbox.addEventListener("change", () => loadLayers().catch(console.error));
+
+loadRuns().catch((error) => {
+  console.error("Error loading runs:", error);
+  statsDiv.textContent = error.message;
+});
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/paulmach/orb/maptile"
+)
+
+// tileKey identifies an encoded vector tile of one level of a run.
+type tileKey struct {
+	run   string
+	level int
+	tile  maptile.Tile
+}
+
+type tileEntry struct {
+	key  tileKey
+	data []byte
+}
+
+// tileCache is a least recently used cache of encoded vector tiles, safe for concurrent use.
+type tileCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // tileEntry values, most recently used first
+	entries  map[tileKey]*list.Element
+}
+
+func newTileCache(capacity int) *tileCache {
+	return &tileCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[tileKey]*list.Element),
+	}
+}
+
+// get returns the cached tile and marks it as most recently used.
+func (c *tileCache) get(key tileKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(tileEntry).data, true
+}
+
+// add caches the tile, evicting the least recently used tile if the cache is full.
+func (c *tileCache) add(key tileKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = tileEntry{key: key, data: data}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(tileEntry{key: key, data: data})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(tileEntry).key)
+	}
+}
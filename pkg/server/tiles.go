@@ -0,0 +1,255 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/exporter"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/orb/simplify"
+)
+
+const (
+	tileBufferPixels = 64   // features are clipped to the tile extended by this many pixels on every side
+	minRoadZoom      = 12   // road edges are only added to tiles at this zoom level or higher
+	minCutEdgeZoom   = 10   // cut edges are only added to tiles at this zoom level or higher, below the cell borders show the cut
+	tileCacheSize    = 4096 // encoded tiles kept in the least recently used tile cache
+	maxTileZoom      = 22   // deepest zoom level a tile is served for
+	edgeBucketSize   = 8.0  // average number of edges per edge index bucket
+	tileSimplify     = 1.0  // douglas peucker threshold in tile pixels
+	tileMinArea      = 1.0  // polygons smaller than this many square pixels are dropped
+	tileMinLength    = 0.25 // lines shorter than this many pixels are dropped
+)
+
+var ErrInvalidTile = errors.New("invalid tile")
+
+// levelGeometry is the per level data needed to build vector tiles, computed once per run and level.
+type levelGeometry struct {
+	cellOf   []int32
+	parentOf []int32 // parent cell id of every cell, -1 at the top level
+	polygons []orb.MultiPolygon
+	bounds   []orb.Bound
+}
+
+func (rn *run) getLevelGeometry(graph *datastructure.Graph, level int) *levelGeometry {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if lg, ok := rn.levelGeometry[level]; ok {
+		return lg
+	}
+
+	lg := &levelGeometry{
+		cellOf:   rn.partition.CellAssignment(level),
+		parentOf: make([]int32, rn.partition.GetCellCount(level)),
+		polygons: partitioner.CellPolygons(graph, rn.partition, level),
+	}
+	for i := range lg.parentOf {
+		lg.parentOf[i] = -1
+	}
+	if level+1 < rn.partition.GetLevelCount() {
+		for nodeID, cellId := range lg.cellOf {
+			lg.parentOf[cellId] = rn.partition.GetCellID(level+1, int32(nodeID))
+		}
+	}
+	lg.bounds = make([]orb.Bound, len(lg.polygons))
+	for i, polygon := range lg.polygons {
+		lg.bounds[i] = polygon.Bound()
+	}
+
+	rn.levelGeometry[level] = lg
+	return lg
+}
+
+// handleTile returns a mapbox vector tile with the layers cells (cell polygons), cut_edges (edges between cells) from
+// zoom level minCutEdgeZoom and roads (all edges with the cell id of their source node) from zoom level minRoadZoom.
+// encoded tiles are kept in the tile cache of the server.
+func (s *Server) handleTile(w http.ResponseWriter, r *http.Request) {
+	rn, level, err := s.getRunLevel(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tile, err := parseTile(r.PathValue("z"), r.PathValue("x"), r.PathValue("y"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := tileKey{run: rn.name, level: level, tile: tile}
+	data, ok := s.tiles.get(key)
+	if !ok {
+		data, err = s.buildTile(rn, level, tile)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.tiles.add(key, data)
+	}
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Write(data)
+}
+
+// buildTile encodes the vector tile of the level of the run.
+func (s *Server) buildTile(rn *run, level int, tile maptile.Tile) ([]byte, error) {
+	lg := rn.getLevelGeometry(s.graph, level)
+	bound := tile.Bound(tileBufferPixels / float64(mvt.DefaultExtent))
+
+	cells := geojson.NewFeatureCollection()
+	for cellId, polygon := range lg.polygons {
+		if len(polygon) == 0 || !lg.bounds[cellId].Intersects(bound) {
+			continue
+		}
+		feature := geojson.NewFeature(polygon.Clone())
+		feature.ID = cellId
+		feature.Properties["cell_id"] = cellId
+		feature.Properties["parent_cell_id"] = lg.parentOf[cellId]
+		cells.Append(feature)
+	}
+
+	cutEdges := geojson.NewFeatureCollection()
+	roads := geojson.NewFeatureCollection()
+	edgeIDs := []int32(nil)
+	if tile.Z >= minCutEdgeZoom {
+		edgeIDs = s.getEdgeIndex().query(bound)
+	}
+	for _, edgeID := range edgeIDs {
+		edge := s.graph.GetOutEdge(edgeID)
+		fromCell, toCell := lg.cellOf[edge.FromNodeID], lg.cellOf[edge.ToNodeID]
+		if fromCell != toCell {
+			feature := geojson.NewFeature(exporter.EdgeGeometry(s.graph, edgeID))
+			feature.ID = edgeID
+			feature.Properties["edge_id"] = edgeID
+			feature.Properties["from_cell_id"] = fromCell
+			feature.Properties["to_cell_id"] = toCell
+			cutEdges.Append(feature)
+		}
+		if tile.Z >= minRoadZoom {
+			feature := geojson.NewFeature(exporter.EdgeGeometry(s.graph, edgeID))
+			feature.ID = edgeID
+			feature.Properties["edge_id"] = edgeID
+			feature.Properties["cell_id"] = fromCell
+			roads.Append(feature)
+		}
+	}
+
+	layers := mvt.NewLayers(map[string]*geojson.FeatureCollection{
+		"cells":     cells,
+		"cut_edges": cutEdges,
+		"roads":     roads,
+	})
+	layers.ProjectToTile(tile)
+	layers.Clip(orb.Bound{
+		Min: orb.Point{-tileBufferPixels, -tileBufferPixels},
+		Max: orb.Point{mvt.DefaultExtent + tileBufferPixels, mvt.DefaultExtent + tileBufferPixels},
+	})
+	layers.Simplify(simplify.DouglasPeucker(tileSimplify))
+	layers.RemoveEmpty(tileMinLength, tileMinArea)
+	sort.Slice(layers, func(i, j int) bool { return layers[i].Name < layers[j].Name })
+
+	return mvt.Marshal(layers)
+}
+
+// parseTile parses the z/x/y path values of a tile request, y may have a .mvt or .pbf extension.
+func parseTile(zStr, xStr, yStr string) (maptile.Tile, error) {
+	yStr = strings.TrimSuffix(strings.TrimSuffix(yStr, ".mvt"), ".pbf")
+	z, errZ := strconv.ParseUint(zStr, 10, 32)
+	x, errX := strconv.ParseUint(xStr, 10, 32)
+	y, errY := strconv.ParseUint(yStr, 10, 32)
+	if errZ != nil || errX != nil || errY != nil || z > maxTileZoom {
+		return maptile.Tile{}, ErrInvalidTile
+	}
+	tile := maptile.New(uint32(x), uint32(y), maptile.Zoom(z))
+	if !tile.Valid() {
+		return maptile.Tile{}, ErrInvalidTile
+	}
+	return tile, nil
+}
+
+// edgeIndex is a uniform grid over the graph bounding box, every bucket holds the edges whose geometry bound overlaps it.
+type edgeIndex struct {
+	bound   orb.Bound
+	cols    int
+	rows    int
+	buckets [][]int32
+}
+
+func (s *Server) getEdgeIndex() *edgeIndex {
+	s.edgeIndexOnce.Do(func() {
+		s.edgeIndex = newEdgeIndex(s.graph)
+	})
+	return s.edgeIndex
+}
+
+func newEdgeIndex(graph *datastructure.Graph) *edgeIndex {
+	edgeCount := graph.GetOutEdgeCount()
+	edgeBounds := make([]orb.Bound, edgeCount)
+
+	idx := &edgeIndex{}
+	for edgeID := int32(0); edgeID < int32(edgeCount); edgeID++ {
+		edgeBounds[edgeID] = exporter.EdgeGeometry(graph, edgeID).Bound()
+		if edgeID == 0 {
+			idx.bound = edgeBounds[edgeID]
+		} else {
+			idx.bound = idx.bound.Union(edgeBounds[edgeID])
+		}
+	}
+
+	side := int(math.Max(1, math.Sqrt(float64(edgeCount)/edgeBucketSize)))
+	idx.cols, idx.rows = side, side
+	idx.buckets = make([][]int32, side*side)
+	for edgeID, b := range edgeBounds {
+		minCol, minRow, maxCol, maxRow := idx.bucketRange(b)
+		for row := minRow; row <= maxRow; row++ {
+			for col := minCol; col <= maxCol; col++ {
+				idx.buckets[row*idx.cols+col] = append(idx.buckets[row*idx.cols+col], int32(edgeID))
+			}
+		}
+	}
+	return idx
+}
+
+func (idx *edgeIndex) bucketRange(b orb.Bound) (int, int, int, int) {
+	width := idx.bound.Max[0] - idx.bound.Min[0]
+	height := idx.bound.Max[1] - idx.bound.Min[1]
+	clamp := func(v float64, size float64, n int) int {
+		if size == 0 {
+			return 0
+		}
+		return max(0, min(n-1, int((v/size)*float64(n))))
+	}
+	return clamp(b.Min[0]-idx.bound.Min[0], width, idx.cols), clamp(b.Min[1]-idx.bound.Min[1], height, idx.rows),
+		clamp(b.Max[0]-idx.bound.Min[0], width, idx.cols), clamp(b.Max[1]-idx.bound.Min[1], height, idx.rows)
+}
+
+// query returns the sorted ids of the edges in the buckets overlapping b.
+func (idx *edgeIndex) query(b orb.Bound) []int32 {
+	if len(idx.buckets) == 0 || !idx.bound.Intersects(b) {
+		return nil
+	}
+	minCol, minRow, maxCol, maxRow := idx.bucketRange(b)
+	edges := make([]int32, 0)
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			edges = append(edges, idx.buckets[row*idx.cols+col]...)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+
+	unique := edges[:0]
+	for i, edgeID := range edges {
+		if i == 0 || edgeID != edges[i-1] {
+			unique = append(unique, edgeID)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,116 @@
+package snap
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/golang/geo/s2"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+)
+
+var (
+	ErrGraphMismatch = errors.New("partition and graph have a different number of nodes")
+)
+
+// CellLocator returns the cell id at every level of the partition for a coordinate.
+// the level 16 s2 cells whose edges all have their endpoints in the same cell at every level are answered
+// from a precomputed lookup table, every other coordinate is snapped to the nearest edge within radius.
+// close to a cell boundary, a table answer can differ from the cells of the nearest edge.
+// a CellLocator is read only and can be used by multiple goroutines.
+type CellLocator struct {
+	index     *EdgeIndex
+	partition *partitioner.MultilevelPartition
+	radius    float64
+
+	// s2 cells of the lookup table sorted by id, tableNodes[i] is a node inside the cell tableCells[i] whose cells are returned.
+	tableCells []s2.CellID
+	tableNodes []int32
+}
+
+// NewCellLocator builds the edge index and lookup table of the graph for the partition.
+// radius is the snapping radius in meters for coordinates not covered by the lookup table.
+func NewCellLocator(graph *datastructure.Graph, partition *partitioner.MultilevelPartition, radius float64) (*CellLocator, error) {
+	if graph.GetNodeCount() != partition.GetNodeCount() {
+		return nil, ErrGraphMismatch
+	}
+
+	cl := &CellLocator{
+		index:      NewEdgeIndex(graph, partition),
+		partition:  partition,
+		radius:     radius,
+		tableCells: make([]s2.CellID, 0),
+		tableNodes: make([]int32, 0),
+	}
+
+	entries := cl.index.entries
+	for start := 0; start < len(entries); {
+		end := start
+		edge := graph.GetOutEdge(entries[start].edgeID)
+		cellNumber := partition.GetCellNumber(edge.FromNodeID)
+		homogeneous := true
+		for ; end < len(entries) && entries[end].cell == entries[start].cell; end++ {
+			edge := graph.GetOutEdge(entries[end].edgeID)
+			if partition.GetCellNumber(edge.FromNodeID) != cellNumber || partition.GetCellNumber(edge.ToNodeID) != cellNumber {
+				homogeneous = false
+			}
+		}
+		if homogeneous {
+			cl.tableCells = append(cl.tableCells, entries[start].cell)
+			cl.tableNodes = append(cl.tableNodes, edge.FromNodeID)
+		}
+		start = end
+	}
+	return cl, nil
+}
+
+// GetTableSize returns the number of s2 cells answered by the lookup table.
+func (cl *CellLocator) GetTableSize() int {
+	return len(cl.tableCells)
+}
+
+// Locate returns the cell id at every level of the partition for lat, lon.
+// returns ErrNoEdgeInRadius if the coordinate is not covered by the lookup table and no edge is within the snapping radius.
+func (cl *CellLocator) Locate(lat, lon float64) ([]int32, error) {
+	cell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lon)).Parent(indexLevel)
+	i := sort.Search(len(cl.tableCells), func(i int) bool { return cl.tableCells[i] >= cell })
+	if i < len(cl.tableCells) && cl.tableCells[i] == cell {
+		cells := make([]int32, cl.partition.GetLevelCount())
+		for level := range cells {
+			cells[level] = cl.partition.GetCellID(level, cl.tableNodes[i])
+		}
+		return cells, nil
+	}
+
+	result, err := cl.index.Snap(lat, lon, cl.radius)
+	if err != nil {
+		return nil, err
+	}
+	return result.Cells, nil
+}
+
+// LocateBatch locates every coordinate with workers goroutines, GOMAXPROCS if workers <= 0.
+// the cells of coordinates that cannot be located are nil.
+func (cl *CellLocator) LocateBatch(coords []datastructure.Coordinate, workers int) [][]int32 {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	cells := make([][]int32, len(coords))
+	batchSize := (len(coords) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(coords); start += batchSize {
+		end := min(start+batchSize, len(coords))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				cells[i], _ = cl.Locate(coords[i].Lat, coords[i].Lon)
+			}
+		}()
+	}
+	wg.Wait()
+	return cells
+}
@@ -0,0 +1,185 @@
+package snap
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/datastructure"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/geo"
+	"github.com/lintang-b-s/navigatorx-partitioner/pkg/partitioner"
+)
+
+const (
+	indexLevel       = 16 // s2 cells of level 16 are about 150 m wide
+	queryMaxCells    = 16
+	earthRadiusMeter = 6371000.0
+)
+
+var (
+	ErrNoEdgeInRadius = errors.New("no edge within the snapping radius")
+)
+
+// Result is the point of the nearest edge to a snapped coordinate.
+type Result struct {
+	EdgeID   int32
+	Point    datastructure.Coordinate // projection of the coordinate on the edge geometry
+	Distance float64                  // meters between the coordinate and Point
+	Offset   float64                  // meters along the edge geometry from the source node to Point
+	Length   float64                  // meters, length of the edge geometry
+	// Cells has the cell id of every level of the node the snapped point is closer to along the edge,
+	// nil if the index was built without a partition.
+	Cells []int32
+}
+
+// EdgeIndex is a spatial index over the edge geometries: every edge is stored under the level 16 s2 cells
+// covering its segments, sorted by cell id, so that the edges near a point are found with a few binary searches.
+type EdgeIndex struct {
+	graph     *datastructure.Graph
+	partition *partitioner.MultilevelPartition
+	entries   []indexEntry
+}
+
+type indexEntry struct {
+	cell   s2.CellID
+	edgeID int32
+}
+
+// NewEdgeIndex indexes every edge of the graph. partition may be nil, otherwise it must belong to the graph
+// and Snap reports the cells of the snapped point.
+func NewEdgeIndex(graph *datastructure.Graph, partition *partitioner.MultilevelPartition) *EdgeIndex {
+	idx := &EdgeIndex{
+		graph:     graph,
+		partition: partition,
+		entries:   make([]indexEntry, 0, graph.GetOutEdgeCount()*2),
+	}
+
+	// with MinLevel = MaxLevel every covering cell is at indexLevel, larger cells are split into their children
+	coverer := &s2.RegionCoverer{MinLevel: indexLevel, MaxLevel: indexLevel, MaxCells: 8}
+	for edgeID := int32(0); edgeID < int32(graph.GetOutEdgeCount()); edgeID++ {
+		points := idx.edgePoints(edgeID)
+		start := len(idx.entries)
+		for i := 0; i+1 < len(points); i++ {
+			segment := s2.Polyline{points[i], points[i+1]}
+			for _, cell := range coverer.Covering(&segment) {
+				idx.entries = append(idx.entries, indexEntry{cell: cell, edgeID: edgeID})
+			}
+		}
+
+		// consecutive segments share cells, keep one entry per cell
+		cells := idx.entries[start:]
+		sort.Slice(cells, func(i, j int) bool { return cells[i].cell < cells[j].cell })
+		unique := start
+		for i := start; i < len(idx.entries); i++ {
+			if i == start || idx.entries[i].cell != idx.entries[unique-1].cell {
+				idx.entries[unique] = idx.entries[i]
+				unique++
+			}
+		}
+		idx.entries = idx.entries[:unique]
+	}
+
+	sort.Slice(idx.entries, func(i, j int) bool {
+		if idx.entries[i].cell != idx.entries[j].cell {
+			return idx.entries[i].cell < idx.entries[j].cell
+		}
+		return idx.entries[i].edgeID < idx.entries[j].edgeID
+	})
+	return idx
+}
+
+// edgePoints returns the geometry of the edge from its source to its target node.
+func (idx *EdgeIndex) edgePoints(edgeID int32) []s2.Point {
+	coords := idx.edgeCoordinates(edgeID)
+	points := make([]s2.Point, len(coords))
+	for i, c := range coords {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(c.Lat, c.Lon))
+	}
+	return points
+}
+
+func (idx *EdgeIndex) edgeCoordinates(edgeID int32) []datastructure.Coordinate {
+	edge := idx.graph.GetOutEdge(edgeID)
+	from := idx.graph.GetNode(edge.FromNodeID)
+	to := idx.graph.GetNode(edge.ToNodeID)
+
+	coords := []datastructure.Coordinate{datastructure.NewCoordinate(from.Lat, from.Lon)}
+	coords = append(coords, idx.graph.GraphStorage.GetPointsInbetween(edgeID)...)
+	return append(coords, datastructure.NewCoordinate(to.Lat, to.Lon))
+}
+
+// Snap returns the point of the edge nearest to lat, lon, considering only edges within radius meters.
+// ties are broken by the smaller edge id. returns ErrNoEdgeInRadius if there is no such edge.
+func (idx *EdgeIndex) Snap(lat, lon, radius float64) (Result, error) {
+	query := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lon))
+	region := s2.CapFromCenterAngle(query, s1.Angle(radius/earthRadiusMeter))
+	coverer := &s2.RegionCoverer{MaxLevel: indexLevel, MaxCells: queryMaxCells}
+
+	best := Result{EdgeID: -1, Distance: math.Inf(1)}
+	seen := make(map[int32]struct{})
+	for _, cell := range coverer.Covering(region) {
+		// covering cells are at indexLevel or above, the index cells they contain lie in [RangeMin, RangeMax]
+		lo, hi := cell.RangeMin(), cell.RangeMax()
+		i := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].cell >= lo })
+		for ; i < len(idx.entries) && idx.entries[i].cell <= hi; i++ {
+			edgeID := idx.entries[i].edgeID
+			if _, ok := seen[edgeID]; ok {
+				continue
+			}
+			seen[edgeID] = struct{}{}
+
+			candidate := idx.project(edgeID, lat, lon)
+			if candidate.Distance < best.Distance || (candidate.Distance == best.Distance && edgeID < best.EdgeID) {
+				best = candidate
+			}
+		}
+	}
+
+	if best.EdgeID == -1 || best.Distance > radius {
+		return Result{}, ErrNoEdgeInRadius
+	}
+	if idx.partition != nil {
+		edge := idx.graph.GetOutEdge(best.EdgeID)
+		nodeID := edge.FromNodeID
+		if best.Offset > best.Length/2 {
+			nodeID = edge.ToNodeID
+		}
+		best.Cells = make([]int32, idx.partition.GetLevelCount())
+		for level := range best.Cells {
+			best.Cells[level] = idx.partition.GetCellID(level, nodeID)
+		}
+	}
+	return best, nil
+}
+
+// project returns the point of the edge geometry closest to lat, lon.
+func (idx *EdgeIndex) project(edgeID int32, lat, lon float64) Result {
+	coords := idx.edgeCoordinates(edgeID)
+	query := datastructure.NewCoordinate(lat, lon)
+
+	result := Result{EdgeID: edgeID, Distance: math.Inf(1)}
+	length := 0.0
+	for i := 0; i+1 < len(coords); i++ {
+		a, b := coords[i], coords[i+1]
+		segmentLength := geo.CalculateHaversineDistance(a.Lat, a.Lon, b.Lat, b.Lon) * 1000
+
+		projected := s2.Project(
+			s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lon)),
+			s2.PointFromLatLng(s2.LatLngFromDegrees(a.Lat, a.Lon)),
+			s2.PointFromLatLng(s2.LatLngFromDegrees(b.Lat, b.Lon)),
+		)
+		ll := s2.LatLngFromPoint(projected)
+		point := datastructure.NewCoordinate(ll.Lat.Degrees(), ll.Lng.Degrees())
+		dist := geo.CalculateHaversineDistance(query.Lat, query.Lon, point.Lat, point.Lon) * 1000
+		if dist < result.Distance {
+			result.Distance = dist
+			result.Point = point
+			result.Offset = length + math.Min(segmentLength, geo.CalculateHaversineDistance(a.Lat, a.Lon, point.Lat, point.Lon)*1000)
+		}
+		length += segmentLength
+	}
+	result.Length = length
+	return result
+}
@@ -0,0 +1,46 @@
+package util
+
+// HilbertIndex returns the position of the cell (x, y) on the hilbert curve filling a 2^order x 2^order grid.
+// x and y must be smaller than 2^order, order must be at most 32.
+func HilbertIndex(x, y uint32, order uint) uint64 {
+	n := uint64(1) << order
+	px, py := uint64(x), uint64(y)
+
+	var d uint64
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint64
+		if px&s != 0 {
+			rx = 1
+		}
+		if py&s != 0 {
+			ry = 1
+		}
+		d += s * s * ((3 * rx) ^ ry)
+
+		// rotate the quadrant so that the curve inside it has the standard orientation
+		if ry == 0 {
+			if rx == 1 {
+				px = n - 1 - px
+				py = n - 1 - py
+			}
+			px, py = py, px
+		}
+	}
+	return d
+}
+
+// MortonIndex returns the z-order curve position of (x, y), the bits of x and y interleaved with x in the even bits.
+func MortonIndex(x, y uint32) uint64 {
+	return spreadBits(x) | spreadBits(y)<<1
+}
+
+// spreadBits inserts a zero bit between every bit of v.
+func spreadBits(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
@@ -5,10 +5,23 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/exp/rand"
 )
 
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(0))
+)
+
+// SetSeed reseeds the random number generator used by this package, e.g. for the QuickSort pivots.
+func SetSeed(seed uint64) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	rng.Seed(seed)
+}
+
 func Min(a, b int) int {
 	if a < b {
 		return a
@@ -51,7 +64,9 @@ func ReverseG[T any](arr []T) []T {
 }
 
 func generateRandomInt(min, max int) int {
-	return min + rand.Intn(max-min)
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return min + rng.Intn(max-min)
 }
 
 func QuickSortG[T any](arr []T, compare func(a, b T) int) []T {